@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This file reads contact and border alerts pasted from bot-net channels
+// and runs the heading search on each, so nobody has to copy coordinates
+// into findheading by hand.  Each bot format is a regular expression with
+// named groups:
+//
+//   - x, y and z, the contact's position, are required
+//   - yaw and pitch, the heading, are required
+//   - speed, the warp, falls back to -speed when missing
+//   - frame, the coordinates' frame, falls back to -frame when missing
+//   - contact, anything identifying the contact, is only shown
+//
+// Formats are added to the user config as
+//
+//	{"alerts": [{"name": "sentry", "pattern": "...", "angles": {"yaw": "compass"}}]}
+//
+// and are tried before the built in ones, first match wins.  A format's
+// angles, when given, override the global angle convention.
+
+type AlertTemplate struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	// Angles is the convention the bot reports headings in
+	Angles *AngleConvention `json:"angles,omitempty"`
+	re     *regexp.Regexp
+}
+
+// DEFAULT_ALERT_TEMPLATES match the border bot's alerts, e.g.
+// "[Border] Contact 1234 crossed into Federation space at x y z heading 45 10 warp 8"
+var DEFAULT_ALERT_TEMPLATES = []AlertTemplate{
+	{
+		Name:    "border",
+		Pattern: `(?i)contact\s+(?P<contact>\S+).*?\bat\s+(?P<x>-?[\d.]+)[\s,]+(?P<y>-?[\d.]+)[\s,]+(?P<z>-?[\d.]+)(?:\s+in\s+(?P<frame>\S+))?.*?\bheading\s+(?P<yaw>-?[\d.]+)[\s,/]+(?P<pitch>-?[\d.]+)(?:.*?\bwarp\s+(?P<speed>[\d.]+))?`,
+	},
+}
+
+// Compile checks the pattern has the groups a heading search needs.
+func (t *AlertTemplate) Compile() error {
+	re, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return fmt.Errorf("alert format %s: %w", t.Name, err)
+	}
+	for _, group := range []string{"x", "y", "z", "yaw", "pitch"} {
+		if re.SubexpIndex(group) < 0 {
+			return fmt.Errorf("alert format %s has no (?P<%s>...) group", t.Name, group)
+		}
+	}
+	if t.Angles != nil {
+		if err := t.Angles.Validate(); err != nil {
+			return fmt.Errorf("alert format %s: %w", t.Name, err)
+		}
+	}
+	t.re = re
+	return nil
+}
+
+type Alert struct {
+	Format  string
+	Contact string
+	Point   Point
+	Frame   string
+	// Yaw and Pitch are as reported, in Angles when set
+	Yaw, Pitch float64
+	Angles     *AngleConvention
+	// Speed is 0 when the alert didn't give one
+	Speed float64
+}
+
+func (a Alert) String() string {
+	contact := "Contact"
+	if a.Contact != "" {
+		contact += " " + a.Contact
+	}
+	frame := ""
+	if a.Frame != "" {
+		frame = " in " + a.Frame
+	}
+	return fmt.Sprintf("%s (%s) at %.2f %.2f %.2f%s heading %g yaw %g pitch warp %.2f", contact, a.Format, a.Point.X, a.Point.Y, a.Point.Z, frame, a.Yaw, a.Pitch, a.Speed)
+}
+
+// ParseAlert matches line against each template in turn.  It returns false
+// when none match.
+func ParseAlert(line string, templates []AlertTemplate) (Alert, bool, error) {
+	for _, template := range templates {
+		match := template.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		group := func(name string) string {
+			if ndx := template.re.SubexpIndex(name); ndx >= 0 {
+				return match[ndx]
+			}
+			return ""
+		}
+		alert := Alert{Format: template.Name, Contact: group("contact"), Frame: group("frame"), Angles: template.Angles}
+		var values [6]float64
+		for ndx, name := range []string{"x", "y", "z", "yaw", "pitch", "speed"} {
+			s := group(name)
+			if s == "" && name == "speed" {
+				continue
+			}
+			value, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return Alert{}, true, fmt.Errorf("alert format %s: cannot parse %s %q", template.Name, name, s)
+			}
+			values[ndx] = value
+		}
+		alert.Point = Point{X: values[0], Y: values[1], Z: values[2]}
+		alert.Yaw, alert.Pitch = values[3], values[4]
+		alert.Speed = values[5]
+		return alert, true, nil
+	}
+	return Alert{}, false, nil
+}
+
+// AlertTemplates returns the formats from the user config followed by the
+// built in ones, compiled.
+func (c *Config) AlertTemplates() ([]AlertTemplate, error) {
+	var templates []AlertTemplate
+	if configPath := c.ResolveConfigPath(); configPath != "" {
+		userConfig, err := ParseUserConfigFromFile(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing user config from file %s: %w", configPath, err)
+		}
+		templates = append(templates, userConfig.Alerts...)
+	}
+	templates = append(templates, DEFAULT_ALERT_TEMPLATES...)
+	for ndx := range templates {
+		if err := templates[ndx].Compile(); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+func runParseAlert(cfg *Config, args []string) error {
+	alertCmd := flag.NewFlagSet("parse-alert", flag.ContinueOnError)
+	alertFile := alertCmd.String("file", "-", "File of alerts, one per line, - for stdin")
+	alertFormat := alertCmd.String("format", "", "Only use the alert format with this name")
+	alertPattern := alertCmd.String("pattern", "", "Regular expression to use instead of the configured formats")
+	alertSpeed := alertCmd.Float64("speed", 0, "Warp Speed to use when the alert doesn't give one")
+	alertFrame := alertCmd.String("frame", GRC_FRAME, "Frame to use when the alert doesn't give one, by default GRC")
+	alertSearch := AddHeadingSearchFlags(alertCmd)
+	alertNumResults := alertCmd.Int("num-results", 5, "Number of results to display for each alert")
+	cfg.AddFlags(alertCmd)
+	if err := parseFlags(alertCmd, args); err != nil {
+		return err
+	}
+	search, err := alertSearch()
+	if err != nil {
+		return err
+	}
+	var templates []AlertTemplate
+	if *alertPattern != "" {
+		template := AlertTemplate{Name: "pattern", Pattern: *alertPattern}
+		if err := template.Compile(); err != nil {
+			return usageErrorf("%s", err)
+		}
+		templates = []AlertTemplate{template}
+	} else {
+		all, err := cfg.AlertTemplates()
+		if err != nil {
+			return err
+		}
+		for _, template := range all {
+			if *alertFormat == "" || strings.EqualFold(template.Name, *alertFormat) {
+				templates = append(templates, template)
+			}
+		}
+		if len(templates) == 0 {
+			return usageErrorf("no alert format named %s", *alertFormat)
+		}
+	}
+	r := io.Reader(os.Stdin)
+	if *alertFile != "-" {
+		f, err := os.Open(*alertFile)
+		if err != nil {
+			return fmt.Errorf("unable to open %s: %w", *alertFile, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+	matched, failed := 0, 0
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		alert, ok, err := ParseAlert(line, templates)
+		if err != nil {
+			log.Printf("line %d: %s", lineNo, err)
+			matched++
+			failed++
+			continue
+		}
+		if !ok {
+			continue
+		}
+		matched++
+		if alert.Speed == 0 {
+			alert.Speed = *alertSpeed
+		}
+		if alert.Frame == "" {
+			alert.Frame = *alertFrame
+		}
+		angles := cfg.Angles
+		if alert.Angles != nil {
+			angles = *alert.Angles
+		}
+		fmt.Println(alert)
+		contact, err := NewContact(alert.Point, alert.Frame, alert.Yaw, alert.Pitch, alert.Speed, angles)
+		if err == nil {
+			err = findHeading(contact, search, *alertNumResults)
+		}
+		if err != nil {
+			log.Printf("line %d: %s", lineNo, err)
+			failed++
+		}
+		fmt.Println()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading alerts from %s: %w", *alertFile, err)
+	}
+	if matched == 0 {
+		return fmt.Errorf("no alerts matched any format")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d alerts failed", failed, matched)
+	}
+	return nil
+}
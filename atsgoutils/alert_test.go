@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestParseAlert(t *testing.T) {
+	sentry := AlertTemplate{
+		Name:    "sentry",
+		Pattern: `SENTRY: (?P<contact>\S+) @ (?P<x>-?[\d.]+),(?P<y>-?[\d.]+),(?P<z>-?[\d.]+) hdg (?P<yaw>[\d.]+) mk (?P<pitch>-?[\d.]+)`,
+		Angles:  &AngleConvention{Yaw: YAW_COMPASS},
+	}
+	templates := append([]AlertTemplate{sentry}, DEFAULT_ALERT_TEMPLATES...)
+	for ndx := range templates {
+		if err := templates[ndx].Compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name  string
+		line  string
+		want  Alert
+		match bool
+		err   bool
+	}{
+		{
+			name:  "border",
+			line:  "[Border] Contact 1234 crossed into Federation space at -9330.04 -101.1 -56.7 heading 84 11 warp 8",
+			want:  Alert{Format: "border", Contact: "1234", Point: Point{X: -9330.04, Y: -101.1, Z: -56.7}, Yaw: 84, Pitch: 11, Speed: 8},
+			match: true,
+		},
+		{
+			name:  "border in a frame without a speed",
+			line:  "[Border] Contact 7 crossed into Romulan space at 1, 2, 3 in rsb-kelrak heading 270/-5",
+			want:  Alert{Format: "border", Contact: "7", Point: Point{X: 1, Y: 2, Z: 3}, Frame: "rsb-kelrak", Yaw: 270, Pitch: -5},
+			match: true,
+		},
+		{
+			name:  "user format first",
+			line:  "SENTRY: ABC @ 1,2,3 hdg 45 mk -10",
+			want:  Alert{Format: "sentry", Contact: "ABC", Point: Point{X: 1, Y: 2, Z: 3}, Yaw: 45, Pitch: -10, Angles: sentry.Angles},
+			match: true,
+		},
+		{
+			name:  "unparseable number",
+			line:  "[Border] Contact 1 crossed into Federation space at 1.2.3 4 5 heading 84 11",
+			match: true,
+			err:   true,
+		},
+		{name: "chatter", line: "Someone says, \"heading home at warp 8\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert, ok, err := ParseAlert(tt.line, templates)
+			if ok != tt.match || (err != nil) != tt.err {
+				t.Fatalf("ParseAlert() matched %t with error %v, want matched %t and error %t", ok, err, tt.match, tt.err)
+			}
+			if alert != tt.want {
+				t.Errorf("ParseAlert() = %+v, want %+v", alert, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertTemplateCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		template AlertTemplate
+		ok       bool
+	}{
+		{name: "default", template: DEFAULT_ALERT_TEMPLATES[0], ok: true},
+		{name: "missing pitch", template: AlertTemplate{Name: "bad", Pattern: `(?P<x>\d+) (?P<y>\d+) (?P<z>\d+) (?P<yaw>\d+)`}},
+		{name: "invalid expression", template: AlertTemplate{Name: "bad", Pattern: `(?P<x>`}},
+		{name: "unknown angles", template: AlertTemplate{Name: "bad", Pattern: DEFAULT_ALERT_TEMPLATES[0].Pattern, Angles: &AngleConvention{Units: "grad"}}},
+	}
+	for _, tt := range tests {
+		if err := tt.template.Compile(); (err == nil) != tt.ok {
+			t.Errorf("%s: Compile() error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
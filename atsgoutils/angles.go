@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// This file converts headings between the conventions different consoles
+// use and the one ProjectHeading works in, degrees of yaw from +X towards +Y
+// and degrees of pitch up from the XY plane.  A convention may instead use
+// radians, compass yaw (from +Y towards +X, i.e. clockwise from north) and
+// pitch measured downwards.
+
+const (
+	ANGLE_DEGREES = "deg"
+	ANGLE_RADIANS = "rad"
+	YAW_MATH      = "math"
+	YAW_COMPASS   = "compass"
+	PITCH_UP      = "up"
+	PITCH_DOWN    = "down"
+)
+
+// Angles is the convention headings are read and printed in, set from the
+// global flags by LoadData.
+var Angles AngleConvention
+
+// AngleConvention is how a console reports headings, each field empty for
+// the default.
+type AngleConvention struct {
+	Units string `json:"units,omitempty"`
+	Yaw   string `json:"yaw,omitempty"`
+	Pitch string `json:"pitch,omitempty"`
+}
+
+func (a AngleConvention) Validate() error {
+	if a.Units != "" && a.Units != ANGLE_DEGREES && a.Units != ANGLE_RADIANS {
+		return fmt.Errorf("angle units must be %s or %s, received %q", ANGLE_DEGREES, ANGLE_RADIANS, a.Units)
+	}
+	if a.Yaw != "" && a.Yaw != YAW_MATH && a.Yaw != YAW_COMPASS {
+		return fmt.Errorf("yaw convention must be %s or %s, received %q", YAW_MATH, YAW_COMPASS, a.Yaw)
+	}
+	if a.Pitch != "" && a.Pitch != PITCH_UP && a.Pitch != PITCH_DOWN {
+		return fmt.Errorf("pitch convention must be %s or %s, received %q", PITCH_UP, PITCH_DOWN, a.Pitch)
+	}
+	return nil
+}
+
+// unit is how many degrees one of the convention's units is.
+func (a AngleConvention) unit() float64 {
+	if a.Units == ANGLE_RADIANS {
+		return 180 / math.Pi
+	}
+	return 1
+}
+
+// ToHeading checks yaw and pitch are in range for the convention and
+// converts them, with the yaw normalised to 0 to 360.
+func (a AngleConvention) ToHeading(yaw, pitch float64) (Heading, error) {
+	if err := a.Validate(); err != nil {
+		return Heading{}, err
+	}
+	if math.IsNaN(yaw) || math.IsInf(yaw, 0) || math.IsNaN(pitch) || math.IsInf(pitch, 0) {
+		return Heading{}, fmt.Errorf("expected yaw and pitch, received %f and %f", yaw, pitch)
+	}
+	yawDegrees, pitchDegrees := yaw*a.unit(), pitch*a.unit()
+	if math.Abs(yawDegrees) > 360 {
+		return Heading{}, fmt.Errorf("yaw must be within a full turn, received %s", a.format(yaw))
+	}
+	if math.Abs(pitchDegrees) > 90 {
+		return Heading{}, fmt.Errorf("pitch must be within a quarter turn of level, received %s", a.format(pitch))
+	}
+	if a.Yaw == YAW_COMPASS {
+		yawDegrees = 90 - yawDegrees
+	}
+	if a.Pitch == PITCH_DOWN {
+		pitchDegrees = -pitchDegrees
+	}
+	return Heading{Yaw: math.Mod(math.Mod(yawDegrees, 360)+360, 360), Pitch: pitchDegrees}, nil
+}
+
+// FromHeading converts h to the convention, with the yaw from 0 to a full
+// turn.
+func (a AngleConvention) FromHeading(h Heading) (yaw, pitch float64) {
+	yaw, pitch = h.Yaw, h.Pitch
+	if a.Yaw == YAW_COMPASS {
+		yaw = 90 - yaw
+	}
+	if a.Pitch == PITCH_DOWN {
+		pitch = -pitch
+	}
+	yaw = math.Mod(math.Mod(yaw, 360)+360, 360)
+	return yaw / a.unit(), pitch / a.unit()
+}
+
+// FromOffsets converts yaw and pitch offsets from a heading, in degrees, to
+// the convention.
+func (a AngleConvention) FromOffsets(yaw, pitch float64) (float64, float64) {
+	if a.Yaw == YAW_COMPASS {
+		yaw = -yaw
+	}
+	if a.Pitch == PITCH_DOWN {
+		pitch = -pitch
+	}
+	return yaw / a.unit(), pitch / a.unit()
+}
+
+func (a AngleConvention) format(angle float64) string {
+	if a.Units == ANGLE_RADIANS {
+		return fmt.Sprintf("%.4f rad", angle)
+	}
+	return fmt.Sprintf("%.2f°", angle)
+}
+
+func (a AngleConvention) formatOffset(angle float64) string {
+	if a.Units == ANGLE_RADIANS {
+		return fmt.Sprintf("%+.4f rad", angle)
+	}
+	return fmt.Sprintf("%+.2f°", angle)
+}
+
+// FormatHeading prints h in the Angles convention.
+func FormatHeading(h Heading) string {
+	yaw, pitch := Angles.FromHeading(h)
+	return fmt.Sprintf("%s yaw %s pitch", Angles.format(yaw), Angles.format(pitch))
+}
+
+// Contact is a ship's reported position and heading, with the position in
+// Frame.
+type Contact struct {
+	Point   Point
+	Frame   string
+	Heading Heading
+	Speed   float64
+}
+
+// NewContact checks a reported heading and speed, reading the yaw and pitch
+// in the given convention.
+func NewContact(p Point, frame string, yaw, pitch, speed float64, angles AngleConvention) (Contact, error) {
+	heading, err := angles.ToHeading(yaw, pitch)
+	if err != nil {
+		return Contact{}, err
+	}
+	if math.IsNaN(speed) || math.IsInf(speed, 0) || speed <= 0 {
+		return Contact{}, fmt.Errorf("speed must be greater than 0, received %f", speed)
+	}
+	if frame == "" {
+		frame = GRC_FRAME
+	}
+	return Contact{Point: p, Frame: frame, Heading: heading, Speed: speed}, nil
+}
+
+// GRC is the contact's position in GRC, NavComp must be loaded when Frame
+// isn't GRC.
+func (c Contact) GRC() (Point, error) {
+	if c.Frame == GRC_FRAME {
+		return c.Point, nil
+	}
+	p, err := ConvertToGRC(c.Point, c.Frame)
+	if err != nil {
+		return Point{}, fmt.Errorf("error converting to GRC: %w", err)
+	}
+	return *p, nil
+}
+
+// AddContactFlags registers -X, -Y, -Z, -yaw, -pitch, -speed and -frame and
+// returns a function building the contact, in the convention given to it,
+// once the flags have been parsed.  All but -frame are required.
+func AddContactFlags(fs *flag.FlagSet) func(angles AngleConvention) (Contact, error) {
+	x := fs.Float64("X", 0, "X Coordinate")
+	y := fs.Float64("Y", 0, "Y Coordinate")
+	z := fs.Float64("Z", 0, "Z Coordinate")
+	speed := fs.Float64("speed", 0, "Warp Speed the object was travelling at")
+	pitch := fs.Float64("pitch", 0, "Pitch of the object, see --angle-units and --pitch-convention")
+	yaw := fs.Float64("yaw", 0, "Yaw of the object, see --angle-units and --yaw-convention")
+	frame := fs.String("frame", GRC_FRAME, "Frame the coordinates are in, by default GRC")
+	return func(angles AngleConvention) (Contact, error) {
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		var missing []string
+		for _, name := range []string{"X", "Y", "Z", "yaw", "pitch", "speed"} {
+			if !set[name] {
+				missing = append(missing, "-"+name)
+			}
+		}
+		if len(missing) > 0 {
+			return Contact{}, usageErrorf("expected %s", joinList(missing))
+		}
+		contact, err := NewContact(Point{X: *x, Y: *y, Z: *z}, *frame, *yaw, *pitch, *speed, angles)
+		if err != nil {
+			return Contact{}, usageErrorf("%s", err)
+		}
+		return contact, nil
+	}
+}
+
+// joinList joins items as "a, b and c".
+func joinList(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestToHeading(t *testing.T) {
+	tests := []struct {
+		name       string
+		convention AngleConvention
+		yaw, pitch float64
+		want       Heading
+		err        bool
+	}{
+		{name: "default", yaw: 84, pitch: 11, want: Heading{Yaw: 84, Pitch: 11}},
+		{name: "negative yaw", yaw: -90, pitch: -11, want: Heading{Yaw: 270, Pitch: -11}},
+		{name: "full turn", yaw: 360, want: Heading{}},
+		{name: "radians", convention: AngleConvention{Units: ANGLE_RADIANS}, yaw: math.Pi / 2, pitch: -math.Pi / 4, want: Heading{Yaw: 90, Pitch: -45}},
+		{name: "compass north", convention: AngleConvention{Yaw: YAW_COMPASS}, yaw: 0, want: Heading{Yaw: 90}},
+		{name: "compass east", convention: AngleConvention{Yaw: YAW_COMPASS}, yaw: 90, want: Heading{Yaw: 0}},
+		{name: "compass west", convention: AngleConvention{Yaw: YAW_COMPASS}, yaw: 270, want: Heading{Yaw: 180}},
+		{name: "pitch down", convention: AngleConvention{Pitch: PITCH_DOWN}, yaw: 10, pitch: 20, want: Heading{Yaw: 10, Pitch: -20}},
+		{name: "all three", convention: AngleConvention{Units: ANGLE_RADIANS, Yaw: YAW_COMPASS, Pitch: PITCH_DOWN}, yaw: math.Pi, pitch: -math.Pi / 6, want: Heading{Yaw: 270, Pitch: 30}},
+		{name: "yaw past a turn", yaw: 361, err: true},
+		{name: "pitch past vertical", pitch: 91, err: true},
+		{name: "radians given degrees", convention: AngleConvention{Units: ANGLE_RADIANS}, yaw: 84, pitch: 11, err: true},
+		{name: "not a number", yaw: math.NaN(), err: true},
+		{name: "unknown units", convention: AngleConvention{Units: "grad"}, err: true},
+		{name: "unknown yaw", convention: AngleConvention{Yaw: "north"}, err: true},
+		{name: "unknown pitch", convention: AngleConvention{Pitch: "level"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heading, err := tt.convention.ToHeading(tt.yaw, tt.pitch)
+			if tt.err {
+				if err == nil {
+					t.Errorf("ToHeading(%g, %g) = %v, want an error", tt.yaw, tt.pitch, heading)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(heading.Yaw-tt.want.Yaw) > 1e-9 || math.Abs(heading.Pitch-tt.want.Pitch) > 1e-9 {
+				t.Errorf("ToHeading(%g, %g) = %v, want %v", tt.yaw, tt.pitch, heading, tt.want)
+			}
+			// Converting back gives the same angles, the yaw up to a full turn
+			yaw, pitch := tt.convention.FromHeading(heading)
+			if math.Abs(math.Remainder(yaw-tt.yaw, 360/tt.convention.unit())) > 1e-9 || math.Abs(pitch-tt.pitch) > 1e-9 {
+				t.Errorf("FromHeading(%v) = %g, %g, want %g, %g", heading, yaw, pitch, tt.yaw, tt.pitch)
+			}
+		})
+	}
+}
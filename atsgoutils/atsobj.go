@@ -1,8 +1,10 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"log"
 	"math"
 	"os"
 	"strings"
@@ -12,19 +14,25 @@ const (
 	PARSEC               = 3085659622.014257
 	LIGHTSPEED           = 29.979246
 	AVG_COCHRANE_DENSITY = 1298.737508
+	KIND_PLANETS         = "planets"
+	KIND_STATIONS        = "stations"
+	KIND_BORDERS         = "borders"
 )
 
 var (
-	Gates = map[string]*AstralBody{
-		"Transwarp Gate U-02": nil,
-		"Transwarp Gate T-08": nil,
-		"Zausta VI":           nil,
-		"Boreth":              nil,
-		"Latinum Galleria":    nil,
-		"Elosian City":        nil,
-		"Clispau IX":          nil,
-		"Kildare XI":          nil,
+	// GateNames may be body IDs, names or aliases
+	GateNames = []string{
+		"Transwarp Gate U-02",
+		"Transwarp Gate T-08",
+		"Zausta VI",
+		"Boreth",
+		"Latinum Galleria",
+		"Elosian City",
+		"Clispau IX",
+		"Kildare XI",
 	}
+	// Gates holds the resolved GateNames keyed by body ID
+	Gates = map[string]*AstralBody{}
 )
 
 type SpaceObject interface {
@@ -36,17 +44,59 @@ type SpaceObject interface {
 }
 
 type ATSData struct {
-	NavcompDB NavcompDB `json:"ATS_Navcomp_DB"`
+	NavcompDB NavcompDB     `json:"ATS_Navcomp_DB"`
+	Index     *SpatialIndex `json:"-"`
+	byID      map[string]*AstralBody
+	lookup    map[string]*AstralBody
+	// Layers are the data file followed by each overlay applied to it
+	Layers []string `json:"-"`
+	// Hash is the SHA-256 of the data file, before any overlays
+	Hash string `json:"-"`
+	// userFrames are added from the user's config by AddUserFrames
+	userFrames []Frame
 }
 
-func (a *ATSData) FindObject(name string) (*AstralBody, error) {
-	for _, empire := range a.NavcompDB.Empires {
-		body, err := empire.FindObject(name)
-		if err == nil {
-			return body, nil
+// bodyLocation records where in the dataset a body was defined.
+type bodyLocation struct {
+	Empire string
+	Kind   string
+	Index  int
+	Body   *AstralBody
+}
+
+func (b bodyLocation) String() string {
+	return fmt.Sprintf("%s/%s[%d] %q", b.Empire, b.Kind, b.Index, b.Body.Name)
+}
+
+// bodyLocations lists every body, empire by empire, planets before stations.
+func (a *ATSData) bodyLocations() []bodyLocation {
+	var locations []bodyLocation
+	for ndx := range a.NavcompDB.Empires {
+		empire := &a.NavcompDB.Empires[ndx]
+		for indx := range empire.Planets {
+			locations = append(locations, bodyLocation{Empire: empire.Name, Kind: KIND_PLANETS, Index: indx, Body: &empire.Planets[indx]})
+		}
+		for indx := range empire.Stations {
+			locations = append(locations, bodyLocation{Empire: empire.Name, Kind: KIND_STATIONS, Index: indx, Body: &empire.Stations[indx]})
 		}
 	}
-	return nil, fmt.Errorf("object %s not found", name)
+	return locations
+}
+
+// BuildIndex (re)builds the spatial index, it must be called again whenever
+// bodies are added or removed.
+func (a *ATSData) BuildIndex() {
+	locations := a.bodyLocations()
+	bodies := make([]*AstralBody, len(locations))
+	for ndx, loc := range locations {
+		bodies[ndx] = loc.Body
+	}
+	a.Index = NewSpatialIndex(bodies)
+}
+
+// FindObject resolves name with the ranked resolver, see ResolveObject.
+func (a *ATSData) FindObject(name string) (*AstralBody, error) {
+	return a.ResolveObject(name, false)
 }
 
 func (a *ATSData) FilterBodies(filter func(AstralBody) bool) []AstralBody {
@@ -58,12 +108,12 @@ func (a *ATSData) FilterBodies(filter func(AstralBody) bool) []AstralBody {
 	return bodies
 }
 
-func (a *ATSData) ResolveObjects(source, target string) (*AstralBody, *AstralBody, error) {
-	sourceObj, err := a.FindObject(source)
+func (a *ATSData) ResolveObjects(source, target string, exact bool) (*AstralBody, *AstralBody, error) {
+	sourceObj, err := a.ResolveObject(source, exact)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error looking up source %s: %w", source, err)
 	}
-	targetObj, err := a.FindObject(target)
+	targetObj, err := a.ResolveObject(target, exact)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error looking up target %s: %w", target, err)
 	}
@@ -71,13 +121,20 @@ func (a *ATSData) ResolveObjects(source, target string) (*AstralBody, *AstralBod
 }
 
 type NavcompDB struct {
-	Version float64  `json:"version"`
-	Empires []Empire `json:"empires"`
+	Version float64             `json:"version"`
+	Empires []Empire            `json:"empires"`
+	Aliases map[string][]string `json:"aliases,omitempty"`
 }
 
 type Empire struct {
 	Name        string       `json:"name"`
 	Description string       `json:"desc"`
+	Government  string       `json:"government,omitempty"`
+	Leader      string       `json:"leader,omitempty"`
+	Homeworld   string       `json:"homeworld,omitempty"`
+	HWX         float64      `json:"hwx,omitempty"`
+	HWY         float64      `json:"hwy,omitempty"`
+	HWZ         float64      `json:"hwz,omitempty"`
 	Borders     []Border     `json:"borders"`
 	Planets     []AstralBody `json:"planets"`
 	Stations    []AstralBody `json:"stations"`
@@ -98,20 +155,6 @@ func (e *Empire) FilterBodies(filter func(AstralBody) bool) []AstralBody {
 	return bodies
 }
 
-func (e Empire) FindObject(name string) (*AstralBody, error) {
-	for _, body := range e.Planets {
-		if body.IsObjectByName(name) {
-			return &body, nil
-		}
-	}
-	for _, body := range e.Stations {
-		if body.IsObjectByName(name) {
-			return &body, nil
-		}
-	}
-	return nil, fmt.Errorf("object %s not found", name)
-}
-
 type Border struct {
 	Name   string  `json:"name"`
 	X      float64 `json:"x"`
@@ -119,6 +162,8 @@ type Border struct {
 	Z      float64 `json:"z"`
 	Radius float64 `json:"radius"`
 	Point  Point   `json:"-"`
+	// Layer is the data file or overlay the border was last set by
+	Layer string `json:"-"`
 }
 
 func (b *Border) CreatePoint() {
@@ -126,17 +171,70 @@ func (b *Border) CreatePoint() {
 }
 
 type AstralBody struct {
-	Name      string  `json:"name"`
-	X         float64 `json:"x"`
-	Y         float64 `json:"y"`
-	Z         float64 `json:"z"`
-	Cochranes float64 `json:"cochranes"`
-	Market    int64   `json:"market"`
-	Point     Point   `json:"-"`
+	ID      string   `json:"id,omitempty"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// TableAliases are the body's entries in the navcomp's alias table,
+	// kept apart from Aliases so writing the data out doesn't copy them
+	// onto the body
+	TableAliases []string `json:"-"`
+	X            float64  `json:"x"`
+	Y            float64  `json:"y"`
+	Z            float64  `json:"z"`
+	Cochranes    float64  `json:"cochranes"`
+	Market       int64    `json:"market"`
+	Point        Point    `json:"-"`
+	// Kind is KIND_PLANETS or KIND_STATIONS and Empire is the empire whose
+	// list the body is in, both are set by IndexBodies
+	Kind   string  `json:"-"`
+	Empire *Empire `json:"-"`
+	// Layer is the data file or overlay the body was last set by
+	Layer string `json:"-"`
+}
+
+// UnmarshalJSON decodes on top of the body's current fields, except that a
+// missing market means the body has none rather than the lowest tier.
+func (a *AstralBody) UnmarshalJSON(rawBytes []byte) error {
+	type astralBody AstralBody
+	fields := struct {
+		*astralBody
+		Market *int64 `json:"market"`
+	}{astralBody: (*astralBody)(a)}
+	if err := json.Unmarshal(rawBytes, &fields); err != nil {
+		return err
+	}
+	a.Market = MARKET_NONE
+	if fields.Market != nil {
+		a.Market = *fields.Market
+	}
+	return nil
+}
+
+// AllAliases is the body's own aliases followed by any from the alias table.
+func (a AstralBody) AllAliases() []string {
+	return appendMissing(append([]string(nil), a.Aliases...), a.TableAliases...)
+}
+
+func (a AstralBody) EmpireName() string {
+	if a.Empire == nil {
+		return ""
+	}
+	return a.Empire.Name
+}
+
+// KindName is "planet" or "station".
+func (a AstralBody) KindName() string {
+	return strings.TrimSuffix(a.Kind, "s")
 }
 
-func (a AstralBody) IsObjectByName(name string) bool {
-	return strings.Contains(strings.ToLower(a.Name), strings.ToLower(name))
+// Label is the body's name with its empire and kind when known, e.g.
+// "Magna Roma (Federation planet)".
+func (a AstralBody) Label() string {
+	details := strings.TrimSpace(a.EmpireName() + " " + a.KindName())
+	if details == "" {
+		return a.Name
+	}
+	return fmt.Sprintf("%s (%s)", a.Name, details)
 }
 
 func (a *AstralBody) CreatePoint() {
@@ -186,34 +284,92 @@ func (p *Point) Distance(p2 Point) float64 {
 	return math.Sqrt(math.Pow(nx, 2) + math.Pow(ny, 2) + math.Pow(nz, 2))
 }
 
-func ParseATSDataFromFile(filename string) (*ATSData, error) {
-	var atsData ATSData
+func (p Point) Sub(p2 Point) Point {
+	return Point{X: p.X - p2.X, Y: p.Y - p2.Y, Z: p.Z - p2.Z}
+}
+
+func (p Point) Add(p2 Point) Point {
+	return Point{X: p.X + p2.X, Y: p.Y + p2.Y, Z: p.Z + p2.Z}
+}
+
+func (p Point) Scale(f float64) Point {
+	return Point{X: p.X * f, Y: p.Y * f, Z: p.Z * f}
+}
+
+func (p Point) Dot(p2 Point) float64 {
+	return p.X*p2.X + p.Y*p2.Y + p.Z*p2.Z
+}
+
+func (p Point) Norm() float64 {
+	return math.Sqrt(p.Dot(p))
+}
+
+// DistanceToLine is the perpendicular distance from p to the line through
+// origin along dir, limited to origin + t*dir for minT <= t <= maxT.  dir
+// must be a unit vector, and the limits may be infinite.
+func (p Point) DistanceToLine(origin, dir Point, minT, maxT float64) float64 {
+	t := math.Max(minT, math.Min(maxT, p.Sub(origin).Dot(dir)))
+	return p.Distance(origin.Add(dir.Scale(t)))
+}
+
+// ParseATSDataFromFile reads filename and applies each overlay on top of it
+// in order, see ApplyOverlay.
+func ParseATSDataFromFile(filename string, overlays ...string) (*ATSData, error) {
 	rawBytes, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file %s: %w", filename, err)
 	}
-	err = json.Unmarshal(rawBytes, &atsData)
+	return ParseATSData(rawBytes, filename, overlays...)
+}
+
+// ParseATSData parses navcomp data read from filename, or the embedded
+// data, and applies each overlay on top of it in order.
+func ParseATSData(rawBytes []byte, filename string, overlays ...string) (*ATSData, error) {
+	var atsData ATSData
+	err := json.Unmarshal(rawBytes, &atsData)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling json: %w", err)
 	}
 	for ndx, empire := range atsData.NavcompDB.Empires {
 		for indx := range empire.Borders {
 			atsData.NavcompDB.Empires[ndx].Borders[indx].CreatePoint()
+			atsData.NavcompDB.Empires[ndx].Borders[indx].Layer = layerName(filename)
 		}
-		for indx, planet := range empire.Planets {
+		for indx := range empire.Planets {
 			atsData.NavcompDB.Empires[ndx].Planets[indx].CreatePoint()
-			_, ok := Gates[planet.Name]
-			if ok {
-				Gates[planet.Name] = &atsData.NavcompDB.Empires[ndx].Planets[indx]
-			}
+			atsData.NavcompDB.Empires[ndx].Planets[indx].Layer = layerName(filename)
 		}
-		for indx, station := range empire.Stations {
+		for indx := range empire.Stations {
 			atsData.NavcompDB.Empires[ndx].Stations[indx].CreatePoint()
-			_, ok := Gates[station.Name]
-			if ok {
-				Gates[station.Name] = &atsData.NavcompDB.Empires[ndx].Stations[indx]
-			}
+			atsData.NavcompDB.Empires[ndx].Stations[indx].Layer = layerName(filename)
+		}
+	}
+	atsData.Layers = []string{filename}
+	atsData.Hash = fmt.Sprintf("%x", sha256.Sum256(rawBytes))
+	for _, overlay := range overlays {
+		// Overlays may refer to bodies by their derived IDs
+		atsData.assignIDs()
+		warnings, err := atsData.ApplyOverlay(overlay, layerName(overlay))
+		if err != nil {
+			return nil, fmt.Errorf("error applying overlay %s: %w", overlay, err)
 		}
+		for _, warning := range warnings {
+			log.Printf("Warning: %s: %s", overlay, warning)
+		}
+		atsData.Layers = append(atsData.Layers, overlay)
 	}
+	atsData.IndexBodies()
 	return &atsData, nil
 }
+
+func (a *ATSData) WriteToFile(filename string) error {
+	rawBytes, err := json.MarshalIndent(a, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error marshalling json: %w", err)
+	}
+	err = os.WriteFile(filename, rawBytes, 0644)
+	if err != nil {
+		return fmt.Errorf("error writing file %s: %w", filename, err)
+	}
+	return nil
+}
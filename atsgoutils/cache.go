@@ -12,6 +12,10 @@ var (
 	routeCache *RouteCache
 )
 
+// CACHE_VERSION is bumped whenever route names change meaning, version 2
+// keys routes by body ID rather than name.
+const CACHE_VERSION = "2"
+
 type RouteCache struct {
 	Version   string           `json:"version"`
 	RouteMap  map[string]Route `json:"routeMap"`
@@ -19,12 +23,25 @@ type RouteCache struct {
 	NumMisses int              `json:"nuMisses"`
 }
 
+// cacheable reports whether routes involving body can be cached.  Bookmarks
+// may have been moved and overlays may not be loaded next time, so only
+// bodies as they are in the data file are.
+func cacheable(body *AstralBody) bool {
+	if body.Kind == KIND_BOOKMARKS {
+		return false
+	}
+	return NavComp == nil || len(NavComp.Layers) == 0 || body.Layer == layerName(NavComp.Layers[0])
+}
+
 func (r *RouteCache) StoreRoute(route Route) {
+	if !cacheable(route.Source) || !cacheable(route.Target) {
+		return
+	}
 	r.RouteMap[route.Name] = route
 }
 
 func (r *RouteCache) GetRouteFromStrings(source, target string) (*Route, error) {
-	sourceObj, targetObj, err := NavComp.ResolveObjects(source, target)
+	sourceObj, targetObj, err := NavComp.ResolveObjects(source, target, false)
 	if err != nil {
 		return nil, fmt.Errorf("error resolving objects: %w", err)
 	}
@@ -34,8 +51,9 @@ func (r *RouteCache) GetRouteFromStrings(source, target string) (*Route, error)
 func (r *RouteCache) GetRouteFromBodies(source, target *AstralBody) (*Route, error) {
 	rName := GetRouteName(source, target)
 	route, ok := r.RouteMap[rName]
-	if ok {
+	if ok && cacheable(source) && cacheable(target) {
 		r.NumHits = r.NumHits + 1
+		route.Relink(NavComp)
 		return &route, nil
 	}
 	// Here we assume that if we can't find it, we're building a route from directs
@@ -43,11 +61,25 @@ func (r *RouteCache) GetRouteFromBodies(source, target *AstralBody) (*Route, err
 	if err != nil {
 		return nil, fmt.Errorf("error getting direct route %s: %w", rName, err)
 	}
-	r.RouteMap[rName] = route
+	r.StoreRoute(route)
 	r.NumMisses = r.NumMisses + 1
 	return &route, nil
 }
 
+// Relink points a route read from the cache back at the loaded bodies, so
+// they carry the fields that aren't stored in the cache like Empire.
+func (r *Route) Relink(a *ATSData) {
+	if body, ok := a.FindByID(r.Source.ID); ok {
+		r.Source = body
+	}
+	if body, ok := a.FindByID(r.Target.ID); ok {
+		r.Target = body
+	}
+	for _, stop := range r.Stops {
+		stop.Relink(a)
+	}
+}
+
 func (r RouteCache) WriteToFile(fname string) error {
 	rbyte, err := json.Marshal(r)
 	if err != nil {
@@ -83,6 +115,12 @@ func LoadCacheFromFile(fname string) (*RouteCache, error) {
 	if err != nil {
 		return nil, err
 	}
+	if r.Version != CACHE_VERSION {
+		if len(r.RouteMap) > 0 {
+			log.Printf("Discarding cache %s, version %q is not %q", fname, r.Version, CACHE_VERSION)
+		}
+		r = RouteCache{Version: CACHE_VERSION}
+	}
 	if r.RouteMap == nil {
 		r.RouteMap = make(map[string]Route)
 	}
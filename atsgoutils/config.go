@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This file works out where the navcomp data and the route cache live.
+// Locations are resolved in order: command line flag, environment variable,
+// the working directory (how the tool has always been run) and finally the
+// XDG base directories.
+
+const (
+	APP_NAME        = "atsgoutils"
+	DATA_FILENAME   = "atsdata.json"
+	CACHE_FILENAME  = "atscache.json"
+	MARKET_FILENAME = "atsmarket.json"
+	DATA_ENV_VAR    = "ATSGOUTILS_DATA"
+	CACHE_ENV_VAR   = "ATSGOUTILS_CACHE"
+	MARKET_ENV_VAR  = "ATSGOUTILS_MARKET"
+)
+
+type Config struct {
+	DataPath       string
+	CachePath      string
+	MarketPath     string
+	OutputFrame    string
+	Angles         AngleConvention
+	ConfigPath     string
+	Overlays       stringList
+	NonInteractive bool
+}
+
+// AddFlags registers the global flags on the given flag set, so they can be
+// given either before or after the subcommand.
+func (c *Config) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.DataPath, "data", c.DataPath, fmt.Sprintf("Path to the navcomp data file (env %s)", DATA_ENV_VAR))
+	fs.StringVar(&c.CachePath, "cache", c.CachePath, fmt.Sprintf("Path to the route cache file (env %s)", CACHE_ENV_VAR))
+	fs.StringVar(&c.MarketPath, "market", c.MarketPath, fmt.Sprintf("Path to the market commodity and price file (env %s)", MARKET_ENV_VAR))
+	fs.StringVar(&c.OutputFrame, "output-frame", c.OutputFrame, "Frame to print coordinates in, GRC by default")
+	fs.StringVar(&c.Angles.Units, "angle-units", c.Angles.Units, "Units yaw and pitch are given and printed in, deg (default) or rad")
+	fs.StringVar(&c.Angles.Yaw, "yaw-convention", c.Angles.Yaw, "math (default) for yaw from +X towards +Y, or compass for clockwise from +Y")
+	fs.StringVar(&c.Angles.Pitch, "pitch-convention", c.Angles.Pitch, "up (default) for positive pitch above the XY plane, or down for below")
+	fs.Var(&c.Overlays, "overlay", fmt.Sprintf("Overlay data file applied on top of the navcomp data, may be repeated (env %s)", OVERLAY_ENV_VAR))
+	fs.StringVar(&c.ConfigPath, "config", c.ConfigPath, fmt.Sprintf("Path to the user config file with frames and bookmarks (env %s)", CONFIG_ENV_VAR))
+	fs.BoolVar(&c.NonInteractive, "non-interactive", c.NonInteractive, "Fail on ambiguous names instead of asking which was meant")
+}
+
+func fileExists(fname string) bool {
+	info, err := os.Stat(fname)
+	return err == nil && !info.IsDir()
+}
+
+func xdgDataDirs() []string {
+	var dirs []string
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+	}
+	if dataHome != "" {
+		dirs = append(dirs, dataHome)
+	}
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// resolveDataFile returns the first of the flag, the environment variable,
+// the working directory and the XDG data directories to name a file, or an
+// error listing every location that was searched.
+func resolveDataFile(flagValue, envVar, filename string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	if env := os.Getenv(envVar); env != "" {
+		return env, nil
+	}
+	candidates := []string{filename}
+	for _, dir := range xdgDataDirs() {
+		candidates = append(candidates, filepath.Join(dir, APP_NAME, filename))
+	}
+	for _, candidate := range candidates {
+		if fileExists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no %s found, searched %s", filename, strings.Join(candidates, ", "))
+}
+
+// ResolveDataPath returns the navcomp data file to load, or an error listing
+// every location that was searched.
+func (c *Config) ResolveDataPath() (string, error) {
+	dataPath, err := resolveDataFile(c.DataPath, DATA_ENV_VAR, DATA_FILENAME)
+	if err != nil {
+		return "", fmt.Errorf("no navcomp data: %w; use --data or %s", err, DATA_ENV_VAR)
+	}
+	return dataPath, nil
+}
+
+// ResolveMarketPath returns the market file to load, which is looked for in
+// the same places as the navcomp data.
+func (c *Config) ResolveMarketPath() (string, error) {
+	marketPath, err := resolveDataFile(c.MarketPath, MARKET_ENV_VAR, MARKET_FILENAME)
+	if err != nil {
+		return "", fmt.Errorf("no market data: %w; use --market or %s", err, MARKET_ENV_VAR)
+	}
+	return marketPath, nil
+}
+
+// ResolveCachePath returns the route cache file to use. The file does not
+// need to exist yet.
+func (c *Config) ResolveCachePath() string {
+	if c.CachePath != "" {
+		return c.CachePath
+	}
+	if env := os.Getenv(CACHE_ENV_VAR); env != "" {
+		return env
+	}
+	if fileExists(CACHE_FILENAME) {
+		return CACHE_FILENAME
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return CACHE_FILENAME
+	}
+	return filepath.Join(cacheDir, APP_NAME, CACHE_FILENAME)
+}
+
+// parseData reads the navcomp data with the given overlays on top.  With
+// fallback set the data built into the binary is used when no data file is
+// found.
+func (c *Config) parseData(fallback bool, overlays ...string) (*ATSData, error) {
+	dataPath, err := c.ResolveDataPath()
+	if err != nil {
+		if !fallback {
+			return nil, err
+		}
+		log.Printf("No navcomp data file found, using the embedded navcomp data")
+		atsData, err := ParseATSData(embeddedData, EMBEDDED_DATA, overlays...)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing embedded ATS Data: %w", err)
+		}
+		return atsData, nil
+	}
+	atsData, err := ParseATSDataFromFile(dataPath, overlays...)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ATS Data from file %s: %w", dataPath, err)
+	}
+	return atsData, nil
+}
+
+// LoadBaseData reads the navcomp data file alone, without overlays or the
+// user's frames, for the subcommands that check or rewrite the file itself.
+// It never falls back to the embedded data, as that isn't the user's file.
+func (c *Config) LoadBaseData() (*ATSData, error) {
+	return c.parseData(false)
+}
+
+// LoadData reads the navcomp data into NavComp, with any overlays applied,
+// along with the user's own frames.
+func (c *Config) LoadData() error {
+	atsData, err := c.parseData(true, c.ResolveOverlayPaths()...)
+	if err != nil {
+		return err
+	}
+	NavComp = atsData
+	log.Printf("NavComp Loaded from %s", strings.Join(atsData.Layers, " + "))
+	if err := c.LoadUserConfig(); err != nil {
+		return err
+	}
+	if c.OutputFrame != "" {
+		frame, err := NavComp.ResolveFrame(c.OutputFrame)
+		if err != nil {
+			return fmt.Errorf("error resolving output frame: %w", err)
+		}
+		OutputFrame = frame
+	}
+	if err := c.Angles.Validate(); err != nil {
+		return usageErrorf("%s", err)
+	}
+	Angles = c.Angles
+	return nil
+}
+
+// Load reads the navcomp data and route cache into NavComp and routeCache.
+// It is called by the subcommands that need them rather than at start up,
+// so that --help and usage errors work without any data present.
+func (c *Config) Load() error {
+	if err := c.LoadData(); err != nil {
+		return err
+	}
+	cachePath := c.ResolveCachePath()
+	r, err := LoadCacheFromFile(cachePath)
+	if err != nil {
+		return fmt.Errorf("error loading cache from file %s: %w", cachePath, err)
+	}
+	routeCache = r
+	log.Println("Route Cache Loaded")
+	return nil
+}
+
+// LoadMarket reads the market file into Markets, linking it to the loaded
+// navcomp.  Without a market file Markets is left empty unless required is
+// set, so tiers can still be searched by number.
+func (c *Config) LoadMarket(required bool) error {
+	marketPath, err := c.ResolveMarketPath()
+	if err != nil {
+		if required {
+			return err
+		}
+		Markets = &MarketDB{}
+		Markets.Link(NavComp)
+		return nil
+	}
+	markets, err := ParseMarketDBFromFile(marketPath)
+	if err != nil {
+		return fmt.Errorf("error parsing market data from file %s: %w", marketPath, err)
+	}
+	for _, warning := range markets.Link(NavComp) {
+		log.Printf("Warning: %s: %s", marketPath, warning)
+	}
+	Markets = markets
+	log.Printf("Markets Loaded from %s", marketPath)
+	return nil
+}
+
+var errUsage = errors.New("usage error")
+
+func usageErrorf(format string, a ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, a...))
+}
+
+const (
+	EXIT_OK    = 0
+	EXIT_ERROR = 1
+	EXIT_USAGE = 2
+)
+
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return EXIT_OK
+	case errors.Is(err, errUsage):
+		return EXIT_USAGE
+	default:
+		return EXIT_ERROR
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdir changes to dir for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestResolveDataFile(t *testing.T) {
+	home := t.TempDir()
+	xdgFile := filepath.Join(home, APP_NAME, DATA_FILENAME)
+	if err := os.MkdirAll(filepath.Dir(xdgFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(xdgFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty, withData := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(withData, DATA_FILENAME), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		flagValue string
+		env       string
+		dir       string
+		xdg       string
+		// want is the path found, or empty for an error
+		want string
+	}{
+		{name: "flag first", flagValue: "flag.json", env: "env.json", dir: withData, xdg: home, want: "flag.json"},
+		{name: "then the environment", env: "env.json", dir: withData, xdg: home, want: "env.json"},
+		{name: "then the working directory", dir: withData, xdg: home, want: DATA_FILENAME},
+		{name: "then XDG_DATA_HOME", dir: empty, xdg: home, want: xdgFile},
+		{name: "nowhere", dir: empty, xdg: empty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(DATA_ENV_VAR, tt.env)
+			t.Setenv("XDG_DATA_HOME", tt.xdg)
+			t.Setenv("XDG_DATA_DIRS", empty)
+			chdir(t, tt.dir)
+			got, err := resolveDataFile(tt.flagValue, DATA_ENV_VAR, DATA_FILENAME)
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), filepath.Join(empty, APP_NAME, DATA_FILENAME)) {
+					t.Errorf("resolveDataFile() = %q, %v, want an error listing the searched locations", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveDataFile() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCachePath(t *testing.T) {
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	chdir(t, t.TempDir())
+
+	t.Setenv(CACHE_ENV_VAR, "")
+	if got, want := (&Config{}).ResolveCachePath(), filepath.Join(cacheHome, APP_NAME, CACHE_FILENAME); got != want {
+		t.Errorf("with no cache anywhere ResolveCachePath() = %q, want %q", got, want)
+	}
+	if err := os.WriteFile(CACHE_FILENAME, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := (&Config{}).ResolveCachePath(); got != CACHE_FILENAME {
+		t.Errorf("with a cache in the working directory ResolveCachePath() = %q", got)
+	}
+	t.Setenv(CACHE_ENV_VAR, "env.json")
+	if got := (&Config{}).ResolveCachePath(); got != "env.json" {
+		t.Errorf("with %s set ResolveCachePath() = %q", CACHE_ENV_VAR, got)
+	}
+	if got := (&Config{CachePath: "flag.json"}).ResolveCachePath(); got != "flag.json" {
+		t.Errorf("with --cache ResolveCachePath() = %q", got)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "no subcommand", args: nil, want: EXIT_USAGE},
+		{name: "unknown subcommand", args: []string{"warp"}, want: EXIT_USAGE},
+		{name: "unknown flag", args: []string{"ono", "-nonsense"}, want: EXIT_USAGE},
+		{name: "missing required flag", args: []string{"ono"}, want: EXIT_USAGE},
+		{name: "help", args: []string{"ono", "-h"}, want: EXIT_OK},
+		{name: "missing data file", args: []string{"--data", "missing.json", "ono", "-source", "vulcan"}, want: EXIT_ERROR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(tt.args)
+			if got := exitCode(err); got != tt.want {
+				t.Errorf("run(%q) exits %d with %v, want %d", tt.args, got, err, tt.want)
+			}
+		})
+	}
+	if got := exitCode(errors.New("anything")); got != EXIT_ERROR {
+		t.Errorf("exitCode(other error) = %d", got)
+	}
+	if got := exitCode(flag.ErrHelp); got != EXIT_OK {
+		t.Errorf("exitCode(flag.ErrHelp) = %d", got)
+	}
+}
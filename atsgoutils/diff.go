@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// This file compares two navcomp datasets so that when a new atsdata.json is
+// published we can see what was added, removed, renamed or moved.
+
+const (
+	DIFF_ADDED   = "added"
+	DIFF_REMOVED = "removed"
+	DIFF_RENAMED = "renamed"
+	DIFF_CHANGED = "changed"
+	// Cochranes and radii are compared with a small tolerance as the data
+	// is often re-exported with a different number of decimal places
+	DIFF_EPSILON = 1e-3
+)
+
+type BodyDiff struct {
+	Status    string      `json:"status"`
+	Name      string      `json:"name"`
+	OldName   string      `json:"oldName,omitempty"`
+	OldEmpire string      `json:"oldEmpire,omitempty"`
+	NewEmpire string      `json:"newEmpire,omitempty"`
+	Shift     float64     `json:"shift,omitempty"`
+	Old       *AstralBody `json:"old,omitempty"`
+	New       *AstralBody `json:"new,omitempty"`
+}
+
+// Changes describes what changed on a body that exists in both datasets.
+// Shift is only set when it is above the diff threshold.
+func (b BodyDiff) Changes() []string {
+	var changes []string
+	if b.Old == nil || b.New == nil {
+		return changes
+	}
+	if b.OldName != "" {
+		changes = append(changes, fmt.Sprintf("renamed from %q", b.OldName))
+	}
+	if b.OldEmpire != b.NewEmpire {
+		changes = append(changes, fmt.Sprintf("empire %s -> %s", b.OldEmpire, b.NewEmpire))
+	}
+	if b.Shift > 0 {
+		changes = append(changes, fmt.Sprintf("moved %.3f (%.3f, %.3f, %.3f) -> (%.3f, %.3f, %.3f)", b.Shift, b.Old.X, b.Old.Y, b.Old.Z, b.New.X, b.New.Y, b.New.Z))
+	}
+	if math.Abs(b.Old.Cochranes-b.New.Cochranes) > DIFF_EPSILON {
+		changes = append(changes, fmt.Sprintf("cochranes %.3f -> %.3f", b.Old.Cochranes, b.New.Cochranes))
+	}
+	if b.Old.Market != b.New.Market {
+		changes = append(changes, fmt.Sprintf("market %d -> %d", b.Old.Market, b.New.Market))
+	}
+	return changes
+}
+
+func (b BodyDiff) String() string {
+	switch b.Status {
+	case DIFF_ADDED:
+		return fmt.Sprintf("+ %s [%s] (%.3f, %.3f, %.3f)", b.Name, b.NewEmpire, b.New.X, b.New.Y, b.New.Z)
+	case DIFF_REMOVED:
+		return fmt.Sprintf("- %s [%s] (%.3f, %.3f, %.3f)", b.Name, b.OldEmpire, b.Old.X, b.Old.Y, b.Old.Z)
+	default:
+		return fmt.Sprintf("~ %s [%s]: %s", b.Name, b.NewEmpire, strings.Join(b.Changes(), ", "))
+	}
+}
+
+type BorderDiff struct {
+	Status string  `json:"status"`
+	Empire string  `json:"empire"`
+	Name   string  `json:"name"`
+	Shift  float64 `json:"shift,omitempty"`
+	Old    *Border `json:"old,omitempty"`
+	New    *Border `json:"new,omitempty"`
+}
+
+func (b BorderDiff) String() string {
+	switch b.Status {
+	case DIFF_ADDED:
+		return fmt.Sprintf("+ %s (%.3f, %.3f, %.3f) radius %.2f", b.Name, b.New.X, b.New.Y, b.New.Z, b.New.Radius)
+	case DIFF_REMOVED:
+		return fmt.Sprintf("- %s (%.3f, %.3f, %.3f) radius %.2f", b.Name, b.Old.X, b.Old.Y, b.Old.Z, b.Old.Radius)
+	}
+	var changes []string
+	if b.Shift > 0 {
+		changes = append(changes, fmt.Sprintf("moved %.3f (%.3f, %.3f, %.3f) -> (%.3f, %.3f, %.3f)", b.Shift, b.Old.X, b.Old.Y, b.Old.Z, b.New.X, b.New.Y, b.New.Z))
+	}
+	if math.Abs(b.Old.Radius-b.New.Radius) > DIFF_EPSILON {
+		changes = append(changes, fmt.Sprintf("radius %.2f -> %.2f", b.Old.Radius, b.New.Radius))
+	}
+	return fmt.Sprintf("~ %s: %s", b.Name, strings.Join(changes, ", "))
+}
+
+type NavcompDiff struct {
+	OldVersion float64      `json:"oldVersion"`
+	NewVersion float64      `json:"newVersion"`
+	Threshold  float64      `json:"threshold"`
+	Bodies     []BodyDiff   `json:"bodies"`
+	Borders    []BorderDiff `json:"borders"`
+	// Warnings are bodies that couldn't be compared, such as a second body
+	// with the same ID
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (d NavcompDiff) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Version %v -> %v\n", d.OldVersion, d.NewVersion)
+	fmt.Fprintf(&sb, "Bodies (%d changes):\n", len(d.Bodies))
+	for _, body := range d.Bodies {
+		fmt.Fprintf(&sb, "\t%s\n", body)
+	}
+	fmt.Fprintf(&sb, "Borders (%d changes):\n", len(d.Borders))
+	lastEmpire := ""
+	for _, border := range d.Borders {
+		if border.Empire != lastEmpire {
+			fmt.Fprintf(&sb, "\t%s:\n", border.Empire)
+			lastEmpire = border.Empire
+		}
+		fmt.Fprintf(&sb, "\t\t%s\n", border)
+	}
+	return sb.String()
+}
+
+// indexBodiesByID keys every body by its ID.  IDs are unique once assigned
+// unless the data itself gives two bodies the same one, the later bodies
+// are then returned as duplicates so they can be reported.
+func indexBodiesByID(a *ATSData) (map[string]bodyLocation, []string, []bodyLocation) {
+	index := make(map[string]bodyLocation)
+	var order []string
+	var duplicates []bodyLocation
+	for _, loc := range a.bodyLocations() {
+		if _, ok := index[loc.Body.ID]; ok {
+			duplicates = append(duplicates, loc)
+			continue
+		}
+		index[loc.Body.ID] = loc
+		order = append(order, loc.Body.ID)
+	}
+	return index, order, duplicates
+}
+
+// compareBody describes how a body found in both datasets changed.
+func compareBody(oldLoc, newLoc bodyLocation, threshold float64) BodyDiff {
+	diff := BodyDiff{
+		Status:    DIFF_CHANGED,
+		Name:      newLoc.Body.Name,
+		OldEmpire: oldLoc.Empire,
+		NewEmpire: newLoc.Empire,
+		Old:       oldLoc.Body,
+		New:       newLoc.Body,
+	}
+	if oldLoc.Body.Name != newLoc.Body.Name {
+		diff.Status = DIFF_RENAMED
+		diff.OldName = oldLoc.Body.Name
+	}
+	if shift := oldLoc.Body.DistanceToObject(*newLoc.Body); shift > threshold {
+		diff.Shift = shift
+	}
+	return diff
+}
+
+func diffBodies(oldData, newData *ATSData, threshold float64) ([]BodyDiff, []string) {
+	var diffs []BodyDiff
+	var warnings []string
+	oldIndex, oldOrder, oldDuplicates := indexBodiesByID(oldData)
+	newIndex, newOrder, newDuplicates := indexBodiesByID(newData)
+	for _, dup := range oldDuplicates {
+		warnings = append(warnings, fmt.Sprintf("%s: %s has the same ID %q as %s, skipped", oldData.Layers[0], dup, dup.Body.ID, oldIndex[dup.Body.ID]))
+	}
+	for _, dup := range newDuplicates {
+		warnings = append(warnings, fmt.Sprintf("%s: %s has the same ID %q as %s, skipped", newData.Layers[0], dup, dup.Body.ID, newIndex[dup.Body.ID]))
+	}
+	var unmatchedOld, unmatchedNew []bodyLocation
+	for _, id := range oldOrder {
+		oldLoc := oldIndex[id]
+		newLoc, ok := newIndex[id]
+		if !ok {
+			unmatchedOld = append(unmatchedOld, oldLoc)
+			continue
+		}
+		if diff := compareBody(oldLoc, newLoc, threshold); len(diff.Changes()) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, id := range newOrder {
+		if _, ok := oldIndex[id]; !ok {
+			unmatchedNew = append(unmatchedNew, newIndex[id])
+		}
+	}
+
+	// Derived IDs include the empire, so a body that changed hands gets a
+	// new one and is matched by name instead
+	matched := make(map[int]bool)
+	var unnamedOld []bodyLocation
+	for _, oldLoc := range unmatchedOld {
+		match := -1
+		for ndx, newLoc := range unmatchedNew {
+			if !matched[ndx] && strings.EqualFold(oldLoc.Body.Name, newLoc.Body.Name) {
+				match = ndx
+				break
+			}
+		}
+		if match < 0 {
+			unnamedOld = append(unnamedOld, oldLoc)
+			continue
+		}
+		matched[match] = true
+		if diff := compareBody(oldLoc, unmatchedNew[match], threshold); len(diff.Changes()) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	// A body that disappeared under one name and appeared under another in
+	// the same place has most likely been renamed
+	for _, oldLoc := range unnamedOld {
+		match := -1
+		closest := threshold
+		for ndx, newLoc := range unmatchedNew {
+			if matched[ndx] {
+				continue
+			}
+			if d := oldLoc.Body.DistanceToObject(*newLoc.Body); d <= closest {
+				match = ndx
+				closest = d
+			}
+		}
+		if match < 0 {
+			diffs = append(diffs, BodyDiff{Status: DIFF_REMOVED, Name: oldLoc.Body.Name, OldEmpire: oldLoc.Empire, Old: oldLoc.Body})
+			continue
+		}
+		matched[match] = true
+		diffs = append(diffs, compareBody(oldLoc, unmatchedNew[match], threshold))
+	}
+	for ndx, newLoc := range unmatchedNew {
+		if !matched[ndx] {
+			diffs = append(diffs, BodyDiff{Status: DIFF_ADDED, Name: newLoc.Body.Name, NewEmpire: newLoc.Empire, New: newLoc.Body})
+		}
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return strings.ToLower(diffs[i].Name) < strings.ToLower(diffs[j].Name)
+	})
+	return diffs, warnings
+}
+
+func indexBordersByEmpire(a *ATSData) map[string]map[string]*Border {
+	index := make(map[string]map[string]*Border)
+	for ndx := range a.NavcompDB.Empires {
+		empire := &a.NavcompDB.Empires[ndx]
+		if _, ok := index[empire.Name]; !ok {
+			index[empire.Name] = make(map[string]*Border)
+		}
+		for indx := range empire.Borders {
+			border := &empire.Borders[indx]
+			if _, ok := index[empire.Name][border.Name]; !ok {
+				index[empire.Name][border.Name] = border
+			}
+		}
+	}
+	return index
+}
+
+func diffBorders(oldData, newData *ATSData, threshold float64) []BorderDiff {
+	var diffs []BorderDiff
+	oldIndex := indexBordersByEmpire(oldData)
+	newIndex := indexBordersByEmpire(newData)
+	for empire, oldBorders := range oldIndex {
+		newBorders := newIndex[empire]
+		for name, oldBorder := range oldBorders {
+			newBorder, ok := newBorders[name]
+			if !ok {
+				diffs = append(diffs, BorderDiff{Status: DIFF_REMOVED, Empire: empire, Name: name, Old: oldBorder})
+				continue
+			}
+			diff := BorderDiff{Status: DIFF_CHANGED, Empire: empire, Name: name, Old: oldBorder, New: newBorder}
+			shift := oldBorder.Point.Distance(newBorder.Point)
+			if shift > threshold {
+				diff.Shift = shift
+			}
+			if diff.Shift > 0 || math.Abs(oldBorder.Radius-newBorder.Radius) > DIFF_EPSILON {
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+	for empire, newBorders := range newIndex {
+		for name, newBorder := range newBorders {
+			if _, ok := oldIndex[empire][name]; !ok {
+				diffs = append(diffs, BorderDiff{Status: DIFF_ADDED, Empire: empire, Name: name, New: newBorder})
+			}
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Empire != diffs[j].Empire {
+			return diffs[i].Empire < diffs[j].Empire
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs
+}
+
+// DiffATSData compares two datasets, reporting coordinate shifts larger than
+// threshold parsecs.
+func DiffATSData(oldData, newData *ATSData, threshold float64) NavcompDiff {
+	bodies, warnings := diffBodies(oldData, newData, threshold)
+	return NavcompDiff{
+		OldVersion: oldData.NavcompDB.Version,
+		NewVersion: newData.NavcompDB.Version,
+		Threshold:  threshold,
+		Bodies:     bodies,
+		Borders:    diffBorders(oldData, newData, threshold),
+		Warnings:   warnings,
+	}
+}
+
+func runDiff(args []string) error {
+	diffCmd := flag.NewFlagSet("diff", flag.ContinueOnError)
+	diffThreshold := diffCmd.Float64("threshold", 0.01, "Minimum coordinate shift in parsecs to report")
+	diffFormat := diffCmd.String("format", "text", "Output format, text or json")
+	diffCmd.Usage = func() {
+		fmt.Fprintln(diffCmd.Output(), "Usage: diff [flags] old.json new.json")
+		diffCmd.PrintDefaults()
+	}
+	if err := parseFlags(diffCmd, args); err != nil {
+		return err
+	}
+	if diffCmd.NArg() != 2 {
+		return usageErrorf("expected old and new data files, received %d arguments", diffCmd.NArg())
+	}
+	if *diffFormat != "text" && *diffFormat != "json" {
+		return usageErrorf("unknown format %q, expected text or json", *diffFormat)
+	}
+	oldData, err := ParseATSDataFromFile(diffCmd.Arg(0))
+	if err != nil {
+		return fmt.Errorf("error parsing ATS Data from file %s: %w", diffCmd.Arg(0), err)
+	}
+	newData, err := ParseATSDataFromFile(diffCmd.Arg(1))
+	if err != nil {
+		return fmt.Errorf("error parsing ATS Data from file %s: %w", diffCmd.Arg(1), err)
+	}
+	diff := DiffATSData(oldData, newData, *diffThreshold)
+	for _, warning := range diff.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+	if *diffFormat == "json" {
+		rbyte, err := json.MarshalIndent(diff, "", "\t")
+		if err != nil {
+			return fmt.Errorf("unable to marshal diff: %w", err)
+		}
+		fmt.Println(string(rbyte))
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// findPlanet returns the ndx'th planet of the named empire.
+func findPlanet(t *testing.T, a *ATSData, empire string, ndx int) *AstralBody {
+	t.Helper()
+	for indx := range a.NavcompDB.Empires {
+		if a.NavcompDB.Empires[indx].Name == empire {
+			return &a.NavcompDB.Empires[indx].Planets[ndx]
+		}
+	}
+	t.Fatalf("no empire %s", empire)
+	return nil
+}
+
+func TestDiffBodies(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, newData *ATSData)
+		// want lists the expected diffs as "status name", and warning a
+		// substring of the only expected warning
+		want    []string
+		warning string
+	}{
+		{
+			name:   "unchanged",
+			change: func(*testing.T, *ATSData) {},
+		},
+		{
+			// atsdata.json has two Meufie V, only the second moves
+			name: "duplicate name moved",
+			change: func(t *testing.T, newData *ATSData) {
+				body := findPlanet(t, newData, "Independent", 37)
+				body.X += 5
+				body.CreatePoint()
+			},
+			want: []string{"changed Meufie V"},
+		},
+		{
+			name: "renamed keeping its ID",
+			change: func(t *testing.T, newData *ATSData) {
+				body := findPlanet(t, newData, "Independent", 54)
+				body.Name = "Spoja Prime"
+				body.X += 50
+				body.CreatePoint()
+			},
+			want: []string{"renamed Spoja Prime"},
+		},
+		{
+			name: "duplicate ID",
+			change: func(t *testing.T, newData *ATSData) {
+				findPlanet(t, newData, "Independent", 55).ID = findPlanet(t, newData, "Independent", 54).ID
+			},
+			want:    []string{"removed Spoja V"},
+			warning: `has the same ID "independent/spoja-v"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldData, err := ParseATSDataFromFile("atsdata.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			newData, err := ParseATSDataFromFile("atsdata.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(t, newData)
+			diffs, warnings := diffBodies(oldData, newData, 0.01)
+			var got []string
+			for _, diff := range diffs {
+				got = append(got, diff.Status+" "+diff.Name)
+			}
+			if !sameNames(got, tt.want) {
+				t.Errorf("got diffs %v, want %v", got, tt.want)
+			}
+			if tt.warning == "" && len(warnings) > 0 {
+				t.Errorf("unexpected warnings %v", warnings)
+			}
+			if tt.warning != "" && (len(warnings) != 1 || !strings.Contains(warnings[0], tt.warning)) {
+				t.Errorf("got warnings %v, want one containing %q", warnings, tt.warning)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// This file builds a copy of atsdata.json into the binary, so it works
+// without a data file.  A data file found by ResolveDataPath always wins
+// over the embedded copy.
+
+const EMBEDDED_DATA = "<embedded>"
+
+//go:embed atsdata.json
+var embeddedData []byte
+
+func (a *ATSData) IsEmbedded() bool {
+	return len(a.Layers) > 0 && a.Layers[0] == EMBEDDED_DATA
+}
+
+func runDataVersion(cfg *Config, args []string) error {
+	versionCmd := flag.NewFlagSet("data version", flag.ContinueOnError)
+	cfg.AddFlags(versionCmd)
+	if err := parseFlags(versionCmd, args); err != nil {
+		return err
+	}
+	if err := cfg.LoadData(); err != nil {
+		return err
+	}
+	source := "file " + NavComp.Layers[0]
+	if NavComp.IsEmbedded() {
+		source = "embedded"
+	}
+	fmt.Printf("Source:   %s\n", source)
+	fmt.Printf("Version:  %g\n", NavComp.NavcompDB.Version)
+	fmt.Printf("SHA-256:  %s\n", NavComp.Hash)
+	fmt.Printf("Bodies:   %d\n", NavComp.Index.Len())
+	if len(NavComp.Layers) > 1 {
+		fmt.Printf("Overlays: %s\n", strings.Join(NavComp.Layers[1:], ", "))
+	}
+	if !NavComp.IsEmbedded() {
+		embedded, err := ParseATSData(embeddedData, EMBEDDED_DATA)
+		if err != nil {
+			return fmt.Errorf("error parsing embedded ATS Data: %w", err)
+		}
+		same := "differs from"
+		if embedded.Hash == NavComp.Hash {
+			same = "matches"
+		}
+		fmt.Printf("The file %s the embedded data, version %g (%s)\n", same, embedded.NavcompDB.Version, embedded.Hash[:12])
+	}
+	return nil
+}
+
+// runData dispatches the data subcommands.
+func runData(cfg *Config, args []string) error {
+	if len(args) < 1 {
+		return usageErrorf("expected data subcommand of version")
+	}
+	switch args[0] {
+	case "version":
+		return runDataVersion(cfg, args[1:])
+	default:
+		return usageErrorf("unknown data subcommand %q, expected version", args[0])
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		output <- buf.String()
+	}()
+	err = f()
+	w.Close()
+	return <-output, err
+}
+
+func TestEmbeddedData(t *testing.T) {
+	rawBytes, err := os.ReadFile(DATA_FILENAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(embeddedData, rawBytes) {
+		t.Fatalf("embedded data differs from %s", DATA_FILENAME)
+	}
+	embedded, err := ParseATSData(embeddedData, EMBEDDED_DATA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !embedded.IsEmbedded() {
+		t.Errorf("embedded data not reported as embedded")
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256(rawBytes)); embedded.Hash != want {
+		t.Errorf("Hash = %s, want %s", embedded.Hash, want)
+	}
+	fromFile, err := ParseATSDataFromFile(DATA_FILENAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromFile.IsEmbedded() || (&ATSData{}).IsEmbedded() {
+		t.Errorf("data not from the binary reported as embedded")
+	}
+	if fromFile.Hash != embedded.Hash {
+		t.Errorf("the same bytes hash differently from a file")
+	}
+}
+
+func TestRunDataVersion(t *testing.T) {
+	navComp, outputFrame, angles := NavComp, OutputFrame, Angles
+	t.Cleanup(func() { NavComp, OutputFrame, Angles = navComp, outputFrame, angles })
+	empty := t.TempDir()
+	t.Setenv(DATA_ENV_VAR, "")
+	t.Setenv(OVERLAY_ENV_VAR, "")
+	t.Setenv(CONFIG_ENV_VAR, "")
+	t.Setenv("XDG_DATA_HOME", empty)
+	t.Setenv("XDG_DATA_DIRS", empty)
+	t.Setenv("XDG_CONFIG_HOME", empty)
+	t.Setenv("HOME", empty)
+
+	same := writeTestFile(t, "same.json", string(embeddedData))
+	changed := writeTestFile(t, "changed.json", strings.Replace(string(embeddedData), `"Vulcan"`, `"Vulcan Prime"`, 1))
+	overlay := writeTestFile(t, "extra.json", `{"empires": [{"name": "Federation", "stations": [{"name": "Secret Outpost", "x": 1, "y": 2, "z": 3}]}]}`)
+	tests := []struct {
+		name string
+		args []string
+		// want and notWant are lines that should and shouldn't be printed
+		want, notWant []string
+	}{
+		{
+			name:    "embedded",
+			args:    []string{"version"},
+			want:    []string{"Source:   embedded"},
+			notWant: []string{"Overlays:", "The file"},
+		},
+		{
+			name: "same as embedded",
+			args: []string{"version", "--data", same},
+			want: []string{"Source:   file " + same, "The file matches the embedded data"},
+		},
+		{
+			name: "edited",
+			args: []string{"version", "--data", changed},
+			want: []string{"Source:   file " + changed, "The file differs from the embedded data"},
+		},
+		{
+			name: "overlay",
+			args: []string{"version", "--overlay", overlay},
+			want: []string{"Source:   embedded", "Overlays: " + overlay},
+		},
+	}
+	chdir(t, empty)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := captureStdout(t, func() error { return runData(&Config{}, tt.args) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(output, line) {
+					t.Errorf("output doesn't contain %q:\n%s", line, output)
+				}
+			}
+			for _, line := range tt.notWant {
+				if strings.Contains(output, line) {
+					t.Errorf("output contains %q:\n%s", line, output)
+				}
+			}
+			summary := fmt.Sprintf("Version:  %g\nSHA-256:  %s\nBodies:   %d\n", NavComp.NavcompDB.Version, NavComp.Hash, NavComp.Index.Len())
+			if NavComp.Index.Len() == 0 || !strings.Contains(output, summary) {
+				t.Errorf("output doesn't contain the summary %q:\n%s", summary, output)
+			}
+		})
+	}
+
+	for _, args := range [][]string{nil, {"verison"}} {
+		if err := runData(&Config{}, args); exitCode(err) != EXIT_USAGE {
+			t.Errorf("runData(%q) = %v, want a usage error", args, err)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// This file writes the navcomp data out in formats other tools can read, so
+// the galaxy can be viewed in a spreadsheet or a 3D viewer.  Bodies are
+// exported as points and borders as sphere meshes.
+
+const (
+	EXPORT_CSV     = "csv"
+	EXPORT_GEOJSON = "geojson"
+	EXPORT_PLY     = "ply"
+	EXPORT_OBJ     = "obj"
+)
+
+type exportBorder struct {
+	Empire string
+	Border *Border
+}
+
+// ExportSet is the bodies and borders selected for export.
+type ExportSet struct {
+	Bodies  []bodyLocation
+	Borders []exportBorder
+	// Frame is the frame coordinates are written in
+	Frame Frame
+}
+
+func (s ExportSet) coords(x, y, z float64) Point {
+	if s.Frame.IsGRC() {
+		return Point{X: x, Y: y, Z: z}
+	}
+	// The data has at most six decimal places, drop the noise the
+	// subtraction adds
+	round := func(v float64) float64 { return math.Round(v*1e6) / 1e6 }
+	p := s.Frame.FromGRC(Point{X: x, Y: y, Z: z})
+	return Point{X: round(p.X), Y: round(p.Y), Z: round(p.Z)}
+}
+
+// NewExportSet selects every body and border, or only those belonging to
+// the given empires when any are supplied.
+func NewExportSet(a *ATSData, empires []string) ExportSet {
+	wanted := func(name string) bool {
+		return len(empires) == 0 || containsFold(empires, name)
+	}
+	set := ExportSet{Frame: OutputFrame}
+	for _, loc := range a.bodyLocations() {
+		if wanted(loc.Empire) {
+			set.Bodies = append(set.Bodies, loc)
+		}
+	}
+	for ndx := range a.NavcompDB.Empires {
+		empire := &a.NavcompDB.Empires[ndx]
+		if !wanted(empire.Name) {
+			continue
+		}
+		for indx := range empire.Borders {
+			set.Borders = append(set.Borders, exportBorder{Empire: empire.Name, Border: &empire.Borders[indx]})
+		}
+	}
+	return set
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (s ExportSet) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"kind", "empire", "name", "x", "y", "z", "cochranes", "market", "radius"})
+	for _, loc := range s.Bodies {
+		body := loc.Body
+		p := s.coords(body.X, body.Y, body.Z)
+		cw.Write([]string{loc.Kind, loc.Empire, body.Name, formatFloat(p.X), formatFloat(p.Y), formatFloat(p.Z), formatFloat(body.Cochranes), strconv.FormatInt(body.Market, 10), ""})
+	}
+	for _, eb := range s.Borders {
+		border := eb.Border
+		p := s.coords(border.X, border.Y, border.Z)
+		cw.Write([]string{KIND_BORDERS, eb.Empire, border.Name, formatFloat(p.X), formatFloat(p.Y), formatFloat(p.Z), "", "", formatFloat(border.Radius)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type Geometry struct {
+	Type        string     `json:"type"`
+	Coordinates [3]float64 `json:"coordinates"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"`
+	Geometry   Geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// WriteGeoJSON writes a GeoJSON style feature collection.  Coordinates are
+// galactic x, y, z rather than longitude and latitude, so this is for our
+// own viewers rather than mapping tools.
+func (s ExportSet) WriteGeoJSON(w io.Writer) error {
+	collection := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for _, loc := range s.Bodies {
+		body := loc.Body
+		p := s.coords(body.X, body.Y, body.Z)
+		collection.Features = append(collection.Features, Feature{
+			Type:     "Feature",
+			Geometry: Geometry{Type: "Point", Coordinates: [3]float64{p.X, p.Y, p.Z}},
+			Properties: map[string]any{
+				"name":      body.Name,
+				"empire":    loc.Empire,
+				"kind":      loc.Kind,
+				"cochranes": body.Cochranes,
+				"market":    body.Market,
+			},
+		})
+	}
+	for _, eb := range s.Borders {
+		border := eb.Border
+		p := s.coords(border.X, border.Y, border.Z)
+		collection.Features = append(collection.Features, Feature{
+			Type:     "Feature",
+			Geometry: Geometry{Type: "Point", Coordinates: [3]float64{p.X, p.Y, p.Z}},
+			Properties: map[string]any{
+				"name":   border.Name,
+				"empire": eb.Empire,
+				"kind":   KIND_BORDERS,
+				"radius": border.Radius,
+			},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(collection)
+}
+
+// SphereMesh builds a UV sphere with the given number of rings and segments.
+// Faces are triangles indexing into the returned vertices.
+func SphereMesh(centre Point, radius float64, rings, segments int) ([]Point, [][3]int) {
+	vertices := []Point{{X: centre.X, Y: centre.Y, Z: centre.Z + radius}}
+	for ring := 1; ring < rings; ring++ {
+		theta := math.Pi * float64(ring) / float64(rings)
+		for seg := 0; seg < segments; seg++ {
+			phi := 2 * math.Pi * float64(seg) / float64(segments)
+			vertices = append(vertices, Point{
+				X: centre.X + radius*math.Sin(theta)*math.Cos(phi),
+				Y: centre.Y + radius*math.Sin(theta)*math.Sin(phi),
+				Z: centre.Z + radius*math.Cos(theta),
+			})
+		}
+	}
+	vertices = append(vertices, Point{X: centre.X, Y: centre.Y, Z: centre.Z - radius})
+	bottom := len(vertices) - 1
+
+	ringStart := func(ring int) int { return 1 + (ring-1)*segments }
+	var faces [][3]int
+	for seg := 0; seg < segments; seg++ {
+		next := (seg + 1) % segments
+		faces = append(faces, [3]int{0, ringStart(1) + seg, ringStart(1) + next})
+		for ring := 1; ring < rings-1; ring++ {
+			a, b := ringStart(ring)+seg, ringStart(ring)+next
+			c, d := ringStart(ring+1)+seg, ringStart(ring+1)+next
+			faces = append(faces, [3]int{a, c, d}, [3]int{a, d, b})
+		}
+		last := ringStart(rings - 1)
+		faces = append(faces, [3]int{bottom, last + next, last + seg})
+	}
+	return vertices, faces
+}
+
+// empireColour gives each empire a stable colour in the point cloud.
+func empireColour(empire string) [3]uint8 {
+	h := fnv.New32a()
+	h.Write([]byte(empire))
+	sum := h.Sum32()
+	return [3]uint8{uint8(sum>>16) | 0x40, uint8(sum>>8) | 0x40, uint8(sum) | 0x40}
+}
+
+type meshVertex struct {
+	Point  Point
+	Colour [3]uint8
+}
+
+type exportMesh struct {
+	Vertices []meshVertex
+	Faces    [][3]int
+	// Groups marks where each named object starts in Vertices and Faces
+	Groups []meshGroup
+}
+
+type meshGroup struct {
+	Name                  string
+	FirstVertex, NumVerts int
+	FirstFace, NumFaces   int
+}
+
+func (s ExportSet) buildMesh(rings, segments int) exportMesh {
+	var mesh exportMesh
+	for _, loc := range s.Bodies {
+		mesh.Groups = append(mesh.Groups, meshGroup{Name: loc.Body.Name, FirstVertex: len(mesh.Vertices), NumVerts: 1, FirstFace: len(mesh.Faces)})
+		mesh.Vertices = append(mesh.Vertices, meshVertex{Point: s.coords(loc.Body.X, loc.Body.Y, loc.Body.Z), Colour: empireColour(loc.Empire)})
+	}
+	for _, eb := range s.Borders {
+		border := eb.Border
+		vertices, faces := SphereMesh(s.coords(border.X, border.Y, border.Z), border.Radius, rings, segments)
+		group := meshGroup{Name: "Border " + border.Name, FirstVertex: len(mesh.Vertices), NumVerts: len(vertices), FirstFace: len(mesh.Faces), NumFaces: len(faces)}
+		colour := empireColour(eb.Empire)
+		for _, v := range vertices {
+			mesh.Vertices = append(mesh.Vertices, meshVertex{Point: v, Colour: colour})
+		}
+		for _, f := range faces {
+			mesh.Faces = append(mesh.Faces, [3]int{f[0] + group.FirstVertex, f[1] + group.FirstVertex, f[2] + group.FirstVertex})
+		}
+		mesh.Groups = append(mesh.Groups, group)
+	}
+	return mesh
+}
+
+func (s ExportSet) WritePLY(w io.Writer, rings, segments int) error {
+	mesh := s.buildMesh(rings, segments)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "ply")
+	fmt.Fprintln(bw, "format ascii 1.0")
+	fmt.Fprintln(bw, "comment ATS navcomp export, bodies are single vertices and borders are spheres")
+	fmt.Fprintf(bw, "element vertex %d\n", len(mesh.Vertices))
+	fmt.Fprintln(bw, "property double x\nproperty double y\nproperty double z")
+	fmt.Fprintln(bw, "property uchar red\nproperty uchar green\nproperty uchar blue")
+	fmt.Fprintf(bw, "element face %d\n", len(mesh.Faces))
+	fmt.Fprintln(bw, "property list uchar int vertex_indices")
+	fmt.Fprintln(bw, "end_header")
+	for _, v := range mesh.Vertices {
+		fmt.Fprintf(bw, "%s %s %s %d %d %d\n", formatFloat(v.Point.X), formatFloat(v.Point.Y), formatFloat(v.Point.Z), v.Colour[0], v.Colour[1], v.Colour[2])
+	}
+	for _, f := range mesh.Faces {
+		fmt.Fprintf(bw, "3 %d %d %d\n", f[0], f[1], f[2])
+	}
+	return bw.Flush()
+}
+
+func (s ExportSet) WriteOBJ(w io.Writer, rings, segments int) error {
+	mesh := s.buildMesh(rings, segments)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# ATS navcomp export, bodies are points and borders are spheres")
+	for _, group := range mesh.Groups {
+		fmt.Fprintf(bw, "o %s\n", group.Name)
+		for _, v := range mesh.Vertices[group.FirstVertex : group.FirstVertex+group.NumVerts] {
+			fmt.Fprintf(bw, "v %s %s %s\n", formatFloat(v.Point.X), formatFloat(v.Point.Y), formatFloat(v.Point.Z))
+		}
+		if group.NumFaces == 0 {
+			// OBJ indices are 1-based
+			fmt.Fprintf(bw, "p %d\n", group.FirstVertex+1)
+			continue
+		}
+		for _, f := range mesh.Faces[group.FirstFace : group.FirstFace+group.NumFaces] {
+			fmt.Fprintf(bw, "f %d %d %d\n", f[0]+1, f[1]+1, f[2]+1)
+		}
+	}
+	return bw.Flush()
+}
+
+func runExport(cfg *Config, args []string) error {
+	exportCmd := flag.NewFlagSet("export", flag.ContinueOnError)
+	exportFormat := exportCmd.String("format", EXPORT_CSV, "Output format: csv, geojson, ply or obj")
+	exportEmpires := exportCmd.String("empire", "", "Comma separated list of empires to export, all by default")
+	exportOutput := exportCmd.String("o", "", "File to write to, stdout by default")
+	exportRings := exportCmd.Int("rings", 8, "Latitude rings in border sphere meshes")
+	exportSegments := exportCmd.Int("segments", 16, "Longitude segments in border sphere meshes")
+	cfg.AddFlags(exportCmd)
+	if err := parseFlags(exportCmd, args); err != nil {
+		return err
+	}
+	if *exportRings < 2 || *exportSegments < 3 {
+		return usageErrorf("spheres need at least 2 rings and 3 segments, received %d and %d", *exportRings, *exportSegments)
+	}
+	empires := splitList(*exportEmpires)
+	var write func(io.Writer, ExportSet) error
+	switch strings.ToLower(*exportFormat) {
+	case EXPORT_CSV:
+		write = func(w io.Writer, s ExportSet) error { return s.WriteCSV(w) }
+	case EXPORT_GEOJSON, "json":
+		write = func(w io.Writer, s ExportSet) error { return s.WriteGeoJSON(w) }
+	case EXPORT_PLY:
+		write = func(w io.Writer, s ExportSet) error { return s.WritePLY(w, *exportRings, *exportSegments) }
+	case EXPORT_OBJ:
+		write = func(w io.Writer, s ExportSet) error { return s.WriteOBJ(w, *exportRings, *exportSegments) }
+	default:
+		return usageErrorf("unknown format %q, expected csv, geojson, ply or obj", *exportFormat)
+	}
+	if err := cfg.LoadData(); err != nil {
+		return err
+	}
+	set := NewExportSet(NavComp, empires)
+	if len(set.Bodies) == 0 && len(set.Borders) == 0 {
+		return fmt.Errorf("nothing to export for empires %s", *exportEmpires)
+	}
+	if *exportOutput == "" {
+		if err := write(os.Stdout, set); err != nil {
+			return fmt.Errorf("unable to export %s: %w", *exportFormat, err)
+		}
+		return nil
+	}
+	f, err := os.Create(*exportOutput)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %w", *exportOutput, err)
+	}
+	if err := write(f, set); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to export %s: %w", *exportFormat, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write %s: %w", *exportOutput, err)
+	}
+	return nil
+}
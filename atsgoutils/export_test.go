@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testExportSet is two bodies and two borders in two empires.
+func testExportSet(t *testing.T, empires ...string) ExportSet {
+	t.Helper()
+	atsData := &ATSData{NavcompDB: NavcompDB{Empires: []Empire{
+		{Name: "Alpha", Planets: []AstralBody{{Name: "Alpha Prime", X: 1, Y: 2, Z: 3, Cochranes: 1500, Market: 2}}, Borders: []Border{{Name: "Alpha", X: 10, Radius: 50}}},
+		{Name: "Beta", Stations: []AstralBody{{Name: "Beta Station", X: -1, Y: -2, Z: -3, Market: MARKET_NONE}}, Borders: []Border{{Name: "Beta", X: 300, Y: 5, Radius: 20}}},
+	}}}
+	for ndx := range atsData.NavcompDB.Empires {
+		for indx := range atsData.NavcompDB.Empires[ndx].Borders {
+			atsData.NavcompDB.Empires[ndx].Borders[indx].CreatePoint()
+		}
+	}
+	atsData.IndexBodies()
+	return NewExportSet(atsData, empires)
+}
+
+func TestSphereMesh(t *testing.T) {
+	centre := Point{X: 10, Y: -20, Z: 5}
+	for _, size := range [][2]int{{2, 3}, {3, 4}, {8, 16}} {
+		rings, segments := size[0], size[1]
+		t.Run(fmt.Sprintf("%dx%d", rings, segments), func(t *testing.T) {
+			vertices, faces := SphereMesh(centre, 7, rings, segments)
+			if want := 2 + (rings-1)*segments; len(vertices) != want {
+				t.Errorf("%d vertices, want %d", len(vertices), want)
+			}
+			if want := 2 * (rings - 1) * segments; len(faces) != want {
+				t.Errorf("%d faces, want %d", len(faces), want)
+			}
+			for ndx, v := range vertices {
+				if d := v.Distance(centre); math.Abs(d-7) > 1e-9 {
+					t.Errorf("vertex %d is %g from the centre, want 7", ndx, d)
+				}
+			}
+			// A closed surface uses each edge once in each direction, and
+			// every face faces outwards
+			edges := make(map[[2]int]int)
+			for ndx, f := range faces {
+				for corner := 0; corner < 3; corner++ {
+					if f[corner] < 0 || f[corner] >= len(vertices) {
+						t.Fatalf("face %d indexes vertex %d of %d", ndx, f[corner], len(vertices))
+					}
+					edges[[2]int{f[corner], f[(corner+1)%3]}]++
+				}
+				a, b, c := vertices[f[0]], vertices[f[1]], vertices[f[2]]
+				u, v := b.Sub(a), c.Sub(a)
+				normal := Point{X: u.Y*v.Z - u.Z*v.Y, Y: u.Z*v.X - u.X*v.Z, Z: u.X*v.Y - u.Y*v.X}
+				if normal.Dot(a.Add(b).Add(c).Scale(1.0/3).Sub(centre)) <= 0 {
+					t.Errorf("face %d %v faces inwards", ndx, f)
+				}
+			}
+			for edge, count := range edges {
+				if count != 1 || edges[[2]int{edge[1], edge[0]}] != 1 {
+					t.Errorf("edge %v is used %d times and reversed %d times, want once each", edge, count, edges[[2]int{edge[1], edge[0]}])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteOBJ(t *testing.T) {
+	set := testExportSet(t)
+	var buf bytes.Buffer
+	if err := set.WriteOBJ(&buf, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+	// Every index must point at a vertex of its own object
+	var objects []string
+	vertices, objectStart := 0, 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "#" {
+			continue
+		}
+		switch fields[0] {
+		case "o":
+			objects = append(objects, strings.Join(fields[1:], " "))
+			objectStart = vertices
+		case "v":
+			vertices++
+		case "p", "f":
+			for _, field := range fields[1:] {
+				index, err := strconv.Atoi(field)
+				if err != nil || index <= objectStart || index > vertices {
+					t.Errorf("%s in %s indexes vertex %s, want %d to %d", fields[0], objects[len(objects)-1], field, objectStart+1, vertices)
+				}
+			}
+		default:
+			t.Errorf("unexpected line %q", scanner.Text())
+		}
+	}
+	want := []string{"Alpha Prime", "Beta Station", "Border Alpha", "Border Beta"}
+	if !sameNames(objects, want) {
+		t.Errorf("objects %v, want %v", objects, want)
+	}
+	if want := 2 + 2*(2+2*4); vertices != want {
+		t.Errorf("%d vertices, want %d", vertices, want)
+	}
+}
+
+func TestWritePLY(t *testing.T) {
+	set := testExportSet(t, "beta")
+	var buf bytes.Buffer
+	if err := set.WritePLY(&buf, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+	header, body, ok := strings.Cut(buf.String(), "end_header\n")
+	if !ok {
+		t.Fatalf("no end_header in %q", buf.String())
+	}
+	var numVertices, numFaces int
+	for _, line := range strings.Split(header, "\n") {
+		fmt.Sscanf(line, "element vertex %d", &numVertices)
+		fmt.Sscanf(line, "element face %d", &numFaces)
+	}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if numVertices != 1+10 || numFaces != 16 || len(lines) != numVertices+numFaces {
+		t.Fatalf("%d vertices, %d faces and %d lines, want 11, 16 and 27", numVertices, numFaces, len(lines))
+	}
+	colour := empireColour("Beta")
+	for _, line := range lines[:numVertices] {
+		if fields := strings.Fields(line); len(fields) != 6 || strings.Join(fields[3:], " ") != fmt.Sprintf("%d %d %d", colour[0], colour[1], colour[2]) {
+			t.Errorf("vertex %q, want Beta's colour %v", line, colour)
+		}
+	}
+	for _, line := range lines[numVertices:] {
+		var n, a, b, c int
+		if _, err := fmt.Sscanf(line, "%d %d %d %d", &n, &a, &b, &c); err != nil || n != 3 || max(a, b, c) >= numVertices || min(a, b, c) < 0 {
+			t.Errorf("face %q, want 3 indices below %d", line, numVertices)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	set := testExportSet(t)
+	set.Frame = Frame{Name: "Alpha", Empire: "Alpha", Origin: Point{X: 10}}
+	var buf bytes.Buffer
+	if err := set.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"kind", "empire", "name", "x", "y", "z", "cochranes", "market", "radius"},
+		{"planets", "Alpha", "Alpha Prime", "-9", "2", "3", "1500", "2", ""},
+		{"stations", "Beta", "Beta Station", "-11", "-2", "-3", "0", "-1", ""},
+		{"borders", "Alpha", "Alpha", "0", "0", "0", "", "", "50"},
+		{"borders", "Beta", "Beta", "290", "5", "0", "", "", "20"},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(want) {
+		t.Errorf("got\n%v\nwant\n%v", records, want)
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	set := testExportSet(t, "alpha")
+	var buf bytes.Buffer
+	if err := set.WriteGeoJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var collection FeatureCollection
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.Features) != 2 {
+		t.Fatalf("%d features, want Alpha's planet and border", len(collection.Features))
+	}
+	planet, border := collection.Features[0], collection.Features[1]
+	if planet.Properties["name"] != "Alpha Prime" || planet.Geometry.Coordinates != [3]float64{1, 2, 3} || planet.Properties["kind"] != KIND_PLANETS {
+		t.Errorf("planet feature %+v", planet)
+	}
+	if border.Properties["name"] != "Alpha" || border.Properties["radius"] != 50.0 || border.Properties["kind"] != KIND_BORDERS {
+		t.Errorf("border feature %+v", border)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+// This file holds the -kind and -empire flags shared by the commands that
+// list bodies, so results can be narrowed to e.g. only Federation stations,
+// and the flags shared by the commands that search along a heading.
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+type BodyFilter struct {
+	Kinds   []string
+	Empires []string
+}
+
+// AddBodyFilterFlags registers -kind and -empire on fs, the returned
+// function builds the filter once the flags have been parsed.
+func AddBodyFilterFlags(fs *flag.FlagSet) func() (BodyFilter, error) {
+	kinds := fs.String("kind", "", "Only include these kinds of body, comma separated: planet, station")
+	empires := fs.String("empire", "", "Only include bodies belonging to these empires, comma separated")
+	return func() (BodyFilter, error) {
+		filter := BodyFilter{Empires: splitList(*empires)}
+		for _, kind := range splitList(*kinds) {
+			normalised, ok := normaliseKind(kind)
+			if !ok || normalised == KIND_BORDERS {
+				return filter, usageErrorf("unknown kind %q, expected planet or station", kind)
+			}
+			filter.Kinds = append(filter.Kinds, normalised)
+		}
+		return filter, nil
+	}
+}
+
+func (f BodyFilter) Matches(body AstralBody) bool {
+	if len(f.Kinds) > 0 && !containsFold(f.Kinds, body.Kind) {
+		return false
+	}
+	if len(f.Empires) > 0 && !containsFold(f.Empires, body.EmpireName()) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddHeadingSearchFlags registers the destination search flags on fs,
+// along with -kind and -empire.  The returned function builds the search,
+// less its source, heading and speed, once the flags have been parsed.
+func AddHeadingSearchFlags(fs *flag.FlagSet) func() (HeadingSearch, error) {
+	sdist := fs.Float64("sdist", 50, "Distance to use as the 'Same Object', you don't get the station -and- planet")
+	distance := fs.Float64("dist", 1000, "How far along the heading to search in Parsecs")
+	maxDeviation := fs.Float64("max-deviation", 15, "Ignore bodies more than this many degrees off the heading")
+	sigma := fs.Float64("sigma", 3, "Expected error in the heading in degrees, bodies further off score lower")
+	yawTolerance := fs.Float64("yaw-tolerance", 0, "Return every body within this many degrees of the yaw, for rounded readings")
+	pitchTolerance := fs.Float64("pitch-tolerance", 0, "Return every body within this many degrees of the pitch, for rounded readings")
+	bodyFilter := AddBodyFilterFlags(fs)
+	return func() (HeadingSearch, error) {
+		filter, err := bodyFilter()
+		if err != nil {
+			return HeadingSearch{}, err
+		}
+		if *distance <= 0 {
+			return HeadingSearch{}, usageErrorf("dist must be greater than 0, received %f", *distance)
+		}
+		if *maxDeviation <= 0 || *maxDeviation >= 90 {
+			return HeadingSearch{}, usageErrorf("max-deviation must be between 0 and 90 degrees, received %f", *maxDeviation)
+		}
+		// Only bodies ahead are searched, so the cone can't be wider
+		if *yawTolerance < 0 || *yawTolerance >= 90 || *pitchTolerance < 0 || *pitchTolerance >= 90 {
+			return HeadingSearch{}, usageErrorf("yaw-tolerance and pitch-tolerance must be between 0 and 90 degrees, received %f and %f", *yawTolerance, *pitchTolerance)
+		}
+		if *sigma <= 0 {
+			return HeadingSearch{}, usageErrorf("sigma must be greater than 0, received %f", *sigma)
+		}
+		return HeadingSearch{
+			Distance:       *distance,
+			SDist:          *sdist,
+			MaxDeviation:   *maxDeviation,
+			Sigma:          *sigma,
+			YawTolerance:   *yawTolerance,
+			PitchTolerance: *pitchTolerance,
+			Filter:         filter.Matches,
+		}, nil
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestBodyFilter(t *testing.T) {
+	federation := &Empire{Name: "Federation"}
+	klingon := &Empire{Name: "Klingon"}
+	bodies := []AstralBody{
+		{Name: "Vulcan", Kind: KIND_PLANETS, Empire: federation},
+		{Name: "Starbase 1", Kind: KIND_STATIONS, Empire: federation},
+		{Name: "Qo'noS", Kind: KIND_PLANETS, Empire: klingon},
+		{Name: "IKB wej qogh puQmo'", Kind: KIND_STATIONS, Empire: klingon},
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no filter", want: []string{"Vulcan", "Starbase 1", "Qo'noS", "IKB wej qogh puQmo'"}},
+		{name: "kind", args: []string{"-kind", "Station"}, want: []string{"Starbase 1", "IKB wej qogh puQmo'"}},
+		{name: "empire", args: []string{"-empire", "klingon"}, want: []string{"Qo'noS", "IKB wej qogh puQmo'"}},
+		{name: "both", args: []string{"-kind", "planets", "-empire", "Federation"}, want: []string{"Vulcan"}},
+		{name: "lists", args: []string{"-kind", "planet, station", "-empire", "Romulan,Federation,"}, want: []string{"Vulcan", "Starbase 1"}},
+		{name: "border", args: []string{"-kind", "border"}, wantErr: true},
+		{name: "unknown kind", args: []string{"-kind", "moon"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			bodyFilter := AddBodyFilterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			filter, err := bodyFilter()
+			if tt.wantErr {
+				if exitCode(err) != EXIT_USAGE {
+					t.Errorf("error = %v, want a usage error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, body := range bodies {
+				if filter.Matches(body) {
+					got = append(got, body.Name)
+				}
+			}
+			if !sameNames(got, tt.want) {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// This file converts coordinates between frames.  Galactic Real Coordinates
+// (GRC) are what the navcomp stores, and every border is also a frame whose
+// origin is the border's centre, which is how pilots read coordinates off
+// their own empire's sensors.
+
+const GRC_FRAME = "grc"
+
+var (
+	// OutputFrame is the frame coordinates are printed in, set with
+	// --output-frame
+	OutputFrame = Frame{Name: GRC_FRAME}
+)
+
+type Frame struct {
+	Name string
+	// Empire is empty for GRC and user frames
+	Empire string
+	// Parent is the frame a user frame was defined in
+	Parent string
+	// Origin is always in GRC, even for user frames with a parent
+	Origin Point
+	User   bool
+}
+
+func (f Frame) IsGRC() bool {
+	return f.Empire == "" && !f.User
+}
+
+func (f Frame) String() string {
+	switch {
+	case f.User:
+		return fmt.Sprintf("%s (user frame in %s)", f.Name, f.Parent)
+	case f.Empire == "":
+		return f.Name
+	}
+	return fmt.Sprintf("%s (%s)", f.Name, f.Empire)
+}
+
+// ToGRC converts p from this frame to GRC.
+func (f Frame) ToGRC(p Point) Point {
+	return p.Add(f.Origin)
+}
+
+// FromGRC converts p from GRC to this frame.
+func (f Frame) FromGRC(p Point) Point {
+	return p.Sub(f.Origin)
+}
+
+// Frames lists GRC followed by every border frame and then the user's own
+// frames.
+func (a *ATSData) Frames() []Frame {
+	frames := []Frame{{Name: GRC_FRAME}}
+	for _, empire := range a.NavcompDB.Empires {
+		for _, border := range empire.Borders {
+			frames = append(frames, Frame{Name: border.Name, Empire: empire.Name, Origin: border.Point})
+		}
+	}
+	return append(frames, a.userFrames...)
+}
+
+type AmbiguousFrameError struct {
+	Query  string
+	Frames []Frame
+}
+
+func (e *AmbiguousFrameError) Error() string {
+	names := make([]string, len(e.Frames))
+	for ndx, frame := range e.Frames {
+		names[ndx] = frame.String()
+	}
+	return fmt.Sprintf("frame %q is ambiguous, it matches %s", e.Query, strings.Join(names, ", "))
+}
+
+type UnknownFrameError struct {
+	Query string
+	// Candidates are the frames whose names are close to Query when Close
+	// is set, otherwise every frame
+	Candidates []Frame
+	Close      bool
+}
+
+func (e *UnknownFrameError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for ndx, frame := range e.Candidates {
+		names[ndx] = frame.String()
+	}
+	if e.Close {
+		return fmt.Sprintf("unknown frame %q, did you mean %s", e.Query, strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("unknown frame %q, frames are %s", e.Query, strings.Join(names, ", "))
+}
+
+// ResolveFrame finds the frame named name, ignoring case.  Converting
+// coordinates in the wrong frame silently gives wrong answers, so unlike
+// body names frame names must match exactly.  An *UnknownFrameError listing
+// the closest names is returned when none do, and an *AmbiguousFrameError
+// when more than one does.
+func (a *ATSData) ResolveFrame(name string) (Frame, error) {
+	query := lookupKey(name)
+	var matches []Frame
+	for _, frame := range a.Frames() {
+		if lookupKey(frame.Name) == query {
+			matches = append(matches, frame)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		candidates, close := a.closestFrames(query)
+		return Frame{}, &UnknownFrameError{Query: name, Candidates: candidates, Close: close}
+	case 1:
+		return matches[0], nil
+	}
+	return Frame{}, &AmbiguousFrameError{Query: name, Frames: matches}
+}
+
+// closestFrames ranks frame names against query the same way as body names,
+// for suggesting what was meant.  Every frame is returned, and close is
+// false, when none are close.
+func (a *ATSData) closestFrames(query string) (candidates []Frame, close bool) {
+	type rankedFrame struct {
+		frame    Frame
+		rank     MatchRank
+		distance int
+	}
+	var ranked []rankedFrame
+	for _, frame := range a.Frames() {
+		rank, distance := rankName(query, frame.Name)
+		if rank != MatchNone {
+			ranked = append(ranked, rankedFrame{frame: frame, rank: rank, distance: distance})
+		}
+	}
+	if len(ranked) == 0 {
+		return a.Frames(), false
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].rank != ranked[j].rank {
+			return ranked[i].rank > ranked[j].rank
+		}
+		return ranked[i].distance < ranked[j].distance
+	})
+	candidates = make([]Frame, len(ranked))
+	for ndx, r := range ranked {
+		candidates[ndx] = r.frame
+	}
+	return candidates, true
+}
+
+// ConvertToGRC converts p from the named frame to GRC.
+func ConvertToGRC(p Point, frame string) (*Point, error) {
+	f, err := NavComp.ResolveFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+	grc := f.ToGRC(p)
+	return &grc, nil
+}
+
+// ConvertFromGRC converts p from GRC to the named frame.
+func ConvertFromGRC(p Point, frame string) (*Point, error) {
+	f, err := NavComp.ResolveFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+	local := f.FromGRC(p)
+	return &local, nil
+}
+
+// FormatPoint prints p, which is in GRC, in the OutputFrame.
+func FormatPoint(p Point) string {
+	local := OutputFrame.FromGRC(p)
+	if OutputFrame.IsGRC() {
+		return fmt.Sprintf("(%.2f, %.2f, %.2f)", local.X, local.Y, local.Z)
+	}
+	return fmt.Sprintf("(%.2f, %.2f, %.2f %s)", local.X, local.Y, local.Z, OutputFrame.Name)
+}
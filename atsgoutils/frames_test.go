@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveFrame(t *testing.T) {
+	atsData := loadTestNavComp(t)
+	tests := []struct {
+		query string
+		want  string
+		// unknown is set when no frame should match
+		unknown bool
+	}{
+		{query: "grc", want: GRC_FRAME},
+		{query: "Federation", want: "Federation"},
+		{query: "  federation ", want: "Federation"},
+		{query: "fed", unknown: true},
+		{query: "Federaton", unknown: true},
+		{query: "cu", unknown: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			frame, err := atsData.ResolveFrame(tt.query)
+			if tt.unknown {
+				var unknown *UnknownFrameError
+				if !errors.As(err, &unknown) {
+					t.Fatalf("ResolveFrame(%q) = %v, %v, want an unknown frame error", tt.query, frame, err)
+				}
+				if len(unknown.Candidates) == 0 {
+					t.Errorf("ResolveFrame(%q) listed no candidates", tt.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveFrame(%q): %s", tt.query, err)
+			}
+			if frame.Name != tt.want {
+				t.Errorf("ResolveFrame(%q) = %s, want %s", tt.query, frame.Name, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ module kcphysics/atsgoutils
 
 go 1.21.6
 
-require github.com/jedib0t/go-pretty v4.3.0+incompatible
+require (
+	github.com/jedib0t/go-pretty v4.3.0+incompatible
+	golang.org/x/term v0.21.0
+)
 
 require (
 	github.com/asaskevich/govalidator v0.0.0-20230301143203-a9d515a09cc2 // indirect
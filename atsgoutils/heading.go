@@ -18,12 +18,31 @@ type Heading struct {
 
 type HeadingResult struct {
 	Distance, Time, ContainingRadius float64
-	BodyOfInterest                   AstralBody
+	// AlongTrack is how far along the heading the body's closest approach
+	// is and Miss is how far from the heading line it passes
+	AlongTrack, Miss float64
+	// Deviation is the angle off the heading in degrees, and Confidence
+	// the percentage likelihood that this is the destination
+	Deviation, Score, Confidence float64
+	// YawOffset and PitchOffset are the body's yaw and pitch from the
+	// source less the heading's, in degrees
+	YawOffset, PitchOffset float64
+	// Reverse is set for bodies behind the contact, Time is then how long
+	// ago it left them
+	Reverse        bool
+	BodyOfInterest AstralBody
 }
 
 func (h HeadingResult) String() string {
-	duration := time.Duration(h.Time * 1e9).Truncate(time.Second)
-	return fmt.Sprintf("%-20s\t%20s\t[%.2f]", h.BodyOfInterest.Name, duration, h.Distance)
+	duration := time.Duration(h.Time * 1e9).Truncate(time.Second).String()
+	if h.Reverse {
+		duration += " ago"
+	}
+	if h.Score > 0 {
+		yawOffset, pitchOffset := Angles.FromOffsets(h.YawOffset, h.PitchOffset)
+		return fmt.Sprintf("%5.1f%%\t%-20s\t%-20s\t%s\t%20s\t[%.2f]\talong %.2f miss %.2f off %s (yaw %s pitch %s)", h.Confidence, h.BodyOfInterest.Name, strings.TrimSpace(h.BodyOfInterest.EmpireName()+" "+h.BodyOfInterest.KindName()), FormatPoint(h.BodyOfInterest.Point), duration, h.Distance, h.AlongTrack, h.Miss, Angles.format(h.Deviation/Angles.unit()), Angles.formatOffset(yawOffset), Angles.formatOffset(pitchOffset))
+	}
+	return fmt.Sprintf("%-20s\t%-20s\t%s\t%20s\t[%.2f]\talong %.2f miss %.2f", h.BodyOfInterest.Name, strings.TrimSpace(h.BodyOfInterest.EmpireName()+" "+h.BodyOfInterest.KindName()), FormatPoint(h.BodyOfInterest.Point), duration, h.Distance, h.AlongTrack, h.Miss)
 }
 
 type ByDistance []HeadingResult
@@ -47,39 +66,18 @@ func ProjectHeading(heading Heading, spoint Point, d float64) *Point {
 	}
 }
 
-func ConvertToGRC(p Point, frame string) (*Point, error) {
-	// Converts from a given frame to Galactic Real Coordinates
-	for _, emp := range NavComp.NavcompDB.Empires {
-		for _, bor := range emp.Borders {
-			if strings.Contains(strings.ToLower(bor.Name), strings.ToLower(frame)) {
-				return &Point{
-					X: p.X + bor.X,
-					Y: p.Y + bor.Y,
-					Z: p.Z + bor.Z,
-				}, nil
-			}
-		}
-	}
-	return nil, fmt.Errorf("unable to find frame %s", frame)
-}
-
-func bourkianDeterminant(p1, p2, s *Point, sd float64) bool {
-	a := math.Pow((p2.X-p1.X), 2) + math.Pow((p2.Y-p1.Y), 2) + math.Pow((p2.Z-p1.Z), 2)
-	b := 2 * ((p2.X-p1.X)*(p1.X-s.X) + (p2.Y-p1.Y)*(p1.Y-s.Y) + (p2.Z-p1.Z)*(p1.Z-s.Z))
-	c := math.Pow(s.X, 2) + math.Pow(s.Y, 2) + math.Pow(s.Z, 2) + math.Pow(p1.X, 2) + math.Pow(p1.Y, 2) + math.Pow(p1.Z, 2) - 2*(s.X*p1.X+s.Y*p1.Y+s.Z*p1.Z) - math.Pow(sd, 2)
-	bourke := math.Pow(b, 2) - 4*a*c
-	return bourke >= 0
+// closestApproach is how far along the segment from origin along the unit
+// vector dir for length parsecs p comes closest, and how far from the
+// segment it is there.
+func closestApproach(origin, dir Point, length float64, p Point) (alongTrack, miss float64) {
+	alongTrack = math.Max(0, math.Min(length, p.Sub(origin).Dot(dir)))
+	return alongTrack, p.Distance(origin.Add(dir.Scale(alongTrack)))
 }
 
-func FilterAstralBodiesByBkAndDist(source, projected *Point, rad, sdist float64) func(astralBody AstralBody) bool {
-	return func(astralBody AstralBody) bool {
-		bkDeterminant := bourkianDeterminant(&astralBody.Point, projected, source, rad)
-		distFilter := astralBody.DistanceToPoint(*source) > sdist
-		return bkDeterminant && distFilter
-	}
-}
-
-func DetermineTimeAndOrder(source *Point, bodies []AstralBody, speed, rad float64) ([]HeadingResult, error) {
+// DetermineTimeAndOrder works out how long each body is from source at
+// speed and orders them by distance.  When dir is given, the along track
+// and miss distances for the heading are filled in too.
+func DetermineTimeAndOrder(source *Point, bodies []AstralBody, speed, rad float64, dir *Point) ([]HeadingResult, error) {
 	var results []HeadingResult
 	for _, body := range bodies {
 		d := body.DistanceToPoint(*source)
@@ -88,9 +86,13 @@ func DetermineTimeAndOrder(source *Point, bodies []AstralBody, speed, rad float6
 			continue
 		}
 		heading := HeadingResult{
-			Distance:       d,
-			Time:           t,
-			BodyOfInterest: body,
+			Distance:         d,
+			Time:             t,
+			ContainingRadius: rad,
+			BodyOfInterest:   body,
+		}
+		if dir != nil {
+			heading.AlongTrack, heading.Miss = closestApproach(*source, *dir, math.Inf(1), body.Point)
 		}
 		results = append(results, heading)
 	}
@@ -98,30 +100,191 @@ func DetermineTimeAndOrder(source *Point, bodies []AstralBody, speed, rad float6
 	return results, nil
 }
 
-func FindObjectAlongLine(x, y, z, yaw, pitch, speed, distance, sdist float64, frame *string) ([]HeadingResult, error) {
+// HeadingSearch scores the bodies ahead of a contact by how likely each is
+// to be where it's going.  Bodies closer to the heading score higher, with
+// the deviation weighted by a normal distribution of width Sigma, and
+// nearer bodies score a little higher than distant ones.
+type HeadingSearch struct {
+	// Source is the contact's position in GRC
+	Source  Point
+	Heading Heading
+	Speed   float64
+	// Distance is how far ahead to look and bodies within SDist of the
+	// source are skipped, both in parsecs
+	Distance, SDist float64
+	// MaxDeviation and Sigma are in degrees
+	MaxDeviation, Sigma float64
+	// YawTolerance and PitchTolerance, in degrees, allow for rounded
+	// readings.  When either is set every body whose yaw and pitch from
+	// the source are within them of the heading is returned, instead of
+	// those within MaxDeviation.  MaxDeviation stands in for whichever of
+	// them is left at zero
+	YawTolerance, PitchTolerance float64
+	// Reverse searches behind the contact for where it came from, Heading
+	// is still the way it's going
+	Reverse bool
+	Filter  func(AstralBody) bool
+}
+
+// searchHeading is the heading to search along, reversed when looking for
+// where the contact came from.
+func (h HeadingSearch) searchHeading() Heading {
+	if !h.Reverse {
+		return h.Heading
+	}
+	return Heading{Yaw: math.Mod(h.Heading.Yaw+180, 360), Pitch: -h.Heading.Pitch}
+}
+
+// UsesCone reports whether the yaw and pitch tolerances are in use.
+func (h HeadingSearch) UsesCone() bool {
+	return h.YawTolerance > 0 || h.PitchTolerance > 0
+}
+
+// Offsets is how far the yaw and pitch from the source to p are from the
+// heading, in degrees, with yaw wrapped to -180 to 180.
+func (h HeadingSearch) Offsets(p Point) (yaw, pitch float64) {
+	d := p.Sub(h.Source)
+	norm := d.Norm()
+	if norm == 0 {
+		return 0, 0
+	}
+	heading := h.searchHeading()
+	yaw = math.Atan2(d.Y, d.X)*180/math.Pi - heading.Yaw
+	yaw = math.Mod(math.Mod(yaw+180, 360)+360, 360) - 180
+	pitch = math.Asin(d.Z/norm)*180/math.Pi - heading.Pitch
+	return yaw, pitch
+}
+
+// tolerances are the yaw and pitch tolerances of the cone, with
+// MaxDeviation for one that isn't set.
+func (h HeadingSearch) tolerances() (yaw, pitch float64) {
+	yaw, pitch = h.YawTolerance, h.PitchTolerance
+	if yaw == 0 {
+		yaw = h.MaxDeviation
+	}
+	if pitch == 0 {
+		pitch = h.MaxDeviation
+	}
+	return yaw, pitch
+}
+
+// InCone reports whether yaw and pitch offsets are within the tolerances.
+func (h HeadingSearch) InCone(yaw, pitch float64) bool {
+	yawTolerance, pitchTolerance := h.tolerances()
+	return math.Abs(yaw) <= yawTolerance && math.Abs(pitch) <= pitchTolerance
+}
+
+// reach is how far from the source a body can be and still be within
+// Distance along the heading and inside the search's angle.
+func (h HeadingSearch) reach() float64 {
+	angle := h.MaxDeviation
+	if h.UsesCone() {
+		// No point of the cone is further off the heading than this
+		yawTolerance, pitchTolerance := h.tolerances()
+		angle = yawTolerance + pitchTolerance
+	}
+	if angle >= 90 {
+		return math.Inf(1)
+	}
+	return h.Distance / math.Cos(Rads(angle))
+}
+
+// Direction is the unit vector to search along.
+func (h HeadingSearch) Direction() Point {
+	return *ProjectHeading(h.searchHeading(), Point{}, 1)
+}
+
+// Deviation is the angle in degrees between the heading and the direction
+// from the source to p, along with how far ahead p is.
+func (h HeadingSearch) Deviation(p Point) (deviation, alongTrack, miss float64) {
+	dir := h.Direction()
+	alongTrack, miss = closestApproach(h.Source, dir, math.Inf(1), p)
+	// closestApproach clamps to the segment, so behind the source the
+	// miss is the distance to the source itself
+	if alongTrack <= 0 {
+		return 180, alongTrack, p.Distance(h.Source)
+	}
+	return math.Atan2(miss, alongTrack) * 180 / math.Pi, alongTrack, miss
+}
+
+// score is the unnormalised likelihood of a body at the given deviation
+// and distance ahead.
+func (h HeadingSearch) score(deviation, alongTrack float64) float64 {
+	angular := math.Exp(-0.5 * math.Pow(deviation/h.Sigma, 2))
+	ahead := 1 - 0.5*alongTrack/h.Distance
+	return angular * ahead
+}
+
+// Rank returns every body ahead within MaxDeviation of the heading, or
+// inside the tolerance cone, and Distance parsecs along it, most likely
+// first, with Confidence as its share of the total score.  With Reverse the
+// bodies behind are ranked instead, as where the contact most likely left.
+func (h HeadingSearch) Rank() ([]HeadingResult, error) {
+	if NavComp == nil || NavComp.Index == nil {
+		return nil, fmt.Errorf("no navcomp data loaded")
+	}
 	var bodies []AstralBody
-	var finalContainingRadius float64
-	source := &Point{X: x, Y: y, Z: z}
-	if frame != nil && *frame != "grc" {
-		s, err := ConvertToGRC(*source, *frame)
-		if err != nil {
-			return nil, fmt.Errorf("cannot find object along line: %w", err)
+	for _, body := range NavComp.Index.WithinRadius(h.Source, h.reach()) {
+		if body.DistanceToPoint(h.Source) <= h.SDist || (h.Filter != nil && !h.Filter(*body)) {
+			continue
 		}
-		source = s
-	}
-	heading := Heading{Yaw: yaw, Pitch: pitch}
-	projectedHeading := ProjectHeading(heading, *source, speed)
-	for crad := float64(0); crad < 12; crad += 2 {
-		filter := FilterAstralBodiesByBkAndDist(source, projectedHeading, crad, sdist)
-		radialBodies := NavComp.FilterBodies(filter)
-		if len(radialBodies) > 0 {
-			bodies = append(bodies, radialBodies...)
-			finalContainingRadius = crad
-			break
+		deviation, alongTrack, _ := h.Deviation(body.Point)
+		yawOffset, pitchOffset := h.Offsets(body.Point)
+		if alongTrack <= 0 || alongTrack > h.Distance {
+			continue
 		}
+		if h.UsesCone() && !h.InCone(yawOffset, pitchOffset) {
+			continue
+		}
+		if !h.UsesCone() && deviation > h.MaxDeviation {
+			continue
+		}
+		bodies = append(bodies, *body)
 	}
 	if len(bodies) == 0 {
-		return nil, fmt.Errorf("no bodies found along line")
+		direction := "ahead"
+		if h.Reverse {
+			direction = "behind"
+		}
+		if h.UsesCone() {
+			yawTolerance, pitchTolerance := h.tolerances()
+			return nil, fmt.Errorf("no bodies found within %.1f degrees yaw and %.1f degrees pitch of the heading and %.0f parsecs %s", yawTolerance, pitchTolerance, h.Distance, direction)
+		}
+		return nil, fmt.Errorf("no bodies found within %.1f degrees of the heading and %.0f parsecs %s", h.MaxDeviation, h.Distance, direction)
+	}
+	dir := h.Direction()
+	results, err := DetermineTimeAndOrder(&h.Source, bodies, h.Speed, 0, &dir)
+	if err != nil {
+		return nil, err
+	}
+	total := 0.0
+	for ndx := range results {
+		result := &results[ndx]
+		result.Deviation, _, _ = h.Deviation(result.BodyOfInterest.Point)
+		result.YawOffset, result.PitchOffset = h.Offsets(result.BodyOfInterest.Point)
+		result.Score = h.score(result.Deviation, result.AlongTrack)
+		result.Reverse = h.Reverse
+		total += result.Score
+	}
+	for ndx := range results {
+		if total > 0 {
+			results[ndx].Confidence = 100 * results[ndx].Score / total
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Distance < results[j].Distance
+	})
+	return results, nil
+}
+
+func PrintHeadingResults(results []HeadingResult, numResults int) {
+	for ndx, result := range results {
+		if ndx >= numResults {
+			break
+		}
+		fmt.Println(result)
 	}
-	return DetermineTimeAndOrder(source, bodies, speed, finalContainingRadius)
 }
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testNavComp builds NavComp from bodies, all Independent planets.
+func testNavComp(bodies ...AstralBody) *ATSData {
+	for ndx := range bodies {
+		bodies[ndx].Market = MARKET_NONE
+		bodies[ndx].CreatePoint()
+	}
+	atsData := &ATSData{NavcompDB: NavcompDB{Empires: []Empire{{Name: "Independent", Planets: bodies}}}}
+	atsData.IndexBodies()
+	NavComp = atsData
+	return atsData
+}
+
+// bodyAt is a body named name distance parsecs from the origin on heading.
+func bodyAt(name string, heading Heading, distance float64) AstralBody {
+	p := ProjectHeading(heading, Point{}, distance)
+	return AstralBody{Name: name, X: p.X, Y: p.Y, Z: p.Z}
+}
+
+func resultNames(results []HeadingResult) []string {
+	names := make([]string, len(results))
+	for ndx, result := range results {
+		names[ndx] = result.BodyOfInterest.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRankConeBoundary(t *testing.T) {
+	testNavComp(
+		bodyAt("inside yaw", Heading{Yaw: 4.9}, 100),
+		bodyAt("outside yaw", Heading{Yaw: -5.1}, 100),
+		bodyAt("inside pitch", Heading{Pitch: -2.9}, 100),
+		bodyAt("outside pitch", Heading{Pitch: 3.1}, 100),
+		bodyAt("inside corner", Heading{Yaw: -4.9, Pitch: 2.9}, 100),
+		bodyAt("outside corner", Heading{Yaw: 5.1, Pitch: 3.1}, 100),
+		bodyAt("too far", Heading{}, 1100),
+		bodyAt("behind", Heading{Yaw: 180}, 100),
+	)
+	search := HeadingSearch{Distance: 1000, Sigma: 3, YawTolerance: 5, PitchTolerance: 3, Speed: 8}
+	results, err := search.Rank()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"inside corner", "inside pitch", "inside yaw"}
+	if got := resultNames(results); !sameNames(got, want) {
+		t.Errorf("Rank() found %v, want %v", got, want)
+	}
+}
+
+func TestRankOneTolerance(t *testing.T) {
+	testNavComp(
+		bodyAt("yaw", Heading{Yaw: 4}, 100),
+		bodyAt("yaw and pitch", Heading{Yaw: -4, Pitch: 10}, 100),
+		// Further than Distance / cos(5) from the source but within
+		// Distance along the heading
+		bodyAt("yaw and pitch far", Heading{Yaw: 4, Pitch: 14}, 1020),
+		bodyAt("pitch", Heading{Pitch: 2}, 100),
+		bodyAt("wide yaw", Heading{Yaw: 6}, 100),
+		bodyAt("wide pitch", Heading{Yaw: 1, Pitch: 16}, 100),
+	)
+	tests := []struct {
+		name   string
+		search HeadingSearch
+		want   []string
+	}{
+		{
+			name:   "yaw",
+			search: HeadingSearch{YawTolerance: 5},
+			want:   []string{"pitch", "yaw", "yaw and pitch", "yaw and pitch far"},
+		},
+		{
+			name:   "pitch",
+			search: HeadingSearch{PitchTolerance: 3},
+			want:   []string{"pitch", "wide yaw", "yaw"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.search.Distance, tt.search.MaxDeviation, tt.search.Sigma, tt.search.Speed = 1000, 15, 3, 8
+			results, err := tt.search.Rank()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resultNames(results); !sameNames(got, tt.want) {
+				t.Errorf("Rank() found %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeadingSearchFlagsRejectWideCones(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{args: []string{"-yaw-tolerance", "89.9", "-pitch-tolerance", "89.9"}, ok: true},
+		{args: []string{"-yaw-tolerance", "90"}},
+		{args: []string{"-yaw-tolerance", "135"}},
+		{args: []string{"-pitch-tolerance", "90"}},
+		{args: []string{"-yaw-tolerance", "-1"}},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		build := AddHeadingSearchFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := build(); (err == nil) != tt.ok {
+			t.Errorf("%v: error %v, want ok %t", tt.args, err, tt.ok)
+		}
+	}
+}
+
+func TestClosestApproach(t *testing.T) {
+	east := Point{X: 1}
+	tests := []struct {
+		name             string
+		p                Point
+		alongTrack, miss float64
+	}{
+		{name: "on the line", p: Point{X: 50}, alongTrack: 50, miss: 0},
+		{name: "beside the line", p: Point{X: 50, Y: 3}, alongTrack: 50, miss: 3},
+		{name: "above the line", p: Point{X: 20, Z: -5.1}, alongTrack: 20, miss: 5.1},
+		{name: "behind the origin", p: Point{X: -3, Y: 4}, alongTrack: 0, miss: 5},
+		{name: "past the end", p: Point{X: 106, Y: 8}, alongTrack: 100, miss: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alongTrack, miss := closestApproach(Point{}, east, 100, tt.p)
+			if math.Abs(alongTrack-tt.alongTrack) > 1e-9 || math.Abs(miss-tt.miss) > 1e-9 {
+				t.Errorf("got along %g miss %g, want along %g miss %g", alongTrack, miss, tt.alongTrack, tt.miss)
+			}
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	testNavComp(
+		bodyAt("dead ahead", Heading{}, 300),
+		bodyAt("slightly off", Heading{Yaw: 3}, 200),
+		bodyAt("well off", Heading{Yaw: 20, Pitch: 5}, 200),
+		bodyAt("too far", Heading{}, 1100),
+		bodyAt("too close", Heading{}, 0.5),
+		bodyAt("behind", Heading{Yaw: 180}, 300),
+		bodyAt("behind and off", Heading{Yaw: 170}, 300),
+	)
+	tests := []struct {
+		name   string
+		search HeadingSearch
+		// want is every body found, most likely first
+		want []string
+	}{
+		{
+			name:   "ahead",
+			search: HeadingSearch{Distance: 1000, SDist: 1, MaxDeviation: 15, Sigma: 5},
+			want:   []string{"dead ahead", "slightly off"},
+		},
+		{
+			name:   "wide",
+			search: HeadingSearch{Distance: 1000, SDist: 1, MaxDeviation: 30, Sigma: 5},
+			want:   []string{"dead ahead", "slightly off", "well off"},
+		},
+		{
+			name:   "reverse",
+			search: HeadingSearch{Distance: 1000, SDist: 1, MaxDeviation: 15, Sigma: 5, Reverse: true},
+			want:   []string{"behind", "behind and off"},
+		},
+		{
+			name:   "filtered",
+			search: HeadingSearch{Distance: 1000, SDist: 1, MaxDeviation: 15, Sigma: 5, Filter: func(body AstralBody) bool { return body.Name != "dead ahead" }},
+			want:   []string{"slightly off"},
+		},
+		{
+			name:   "nothing within reach",
+			search: HeadingSearch{Distance: 100, SDist: 1, MaxDeviation: 1, Sigma: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.search.Speed = 8
+			results, err := tt.search.Rank()
+			if len(tt.want) == 0 {
+				if err == nil {
+					t.Errorf("Rank() found %v, want an error", resultNames(results))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			total := 0.0
+			for _, result := range results {
+				got = append(got, result.BodyOfInterest.Name)
+				total += result.Confidence
+			}
+			if !sameNames(got, tt.want) {
+				t.Errorf("Rank() found %v, want %v", got, tt.want)
+			}
+			if math.Abs(total-100) > 1e-9 {
+				t.Errorf("confidences add up to %g, want 100", total)
+			}
+		})
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	// All but "far ahead" are 300 parsecs along the heading, so only their
+	// deviation differs
+	off := func(name string, deviation float64) AstralBody {
+		return AstralBody{Name: name, X: 300, Y: 300 * math.Tan(Rads(deviation))}
+	}
+	testNavComp(off("ahead", 0), off("3 off", 3), off("6 off", 6), AstralBody{Name: "far ahead", X: 600})
+	// relative is each body's score against "ahead": a normal falloff of
+	// width Sigma, times 0.7/0.85 for being twice as far
+	tests := []struct {
+		sigma    float64
+		want     []string
+		relative map[string]float64
+	}{
+		{
+			sigma:    3,
+			want:     []string{"ahead", "far ahead", "3 off", "6 off"},
+			relative: map[string]float64{"ahead": 1, "far ahead": 0.7 / 0.85, "3 off": math.Exp(-0.5), "6 off": math.Exp(-2)},
+		},
+		{
+			// A wider sigma forgives the deviation more than the distance
+			sigma:    6,
+			want:     []string{"ahead", "3 off", "far ahead", "6 off"},
+			relative: map[string]float64{"ahead": 1, "far ahead": 0.7 / 0.85, "3 off": math.Exp(-0.125), "6 off": math.Exp(-0.5)},
+		},
+	}
+	for _, tt := range tests {
+		search := HeadingSearch{Distance: 1000, SDist: 1, MaxDeviation: 15, Sigma: tt.sigma, Speed: 8}
+		results, err := search.Rank()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		var ahead float64
+		for _, result := range results {
+			got = append(got, result.BodyOfInterest.Name)
+			if result.BodyOfInterest.Name == "ahead" {
+				ahead = result.Confidence
+			}
+		}
+		if !sameNames(got, tt.want) {
+			t.Errorf("sigma %g: Rank() = %v, want %v", tt.sigma, got, tt.want)
+		}
+		for _, result := range results {
+			name := result.BodyOfInterest.Name
+			if got := result.Confidence / ahead; math.Abs(got-tt.relative[name]) > 1e-9 {
+				t.Errorf("sigma %g: %s scores %g of ahead, want %g", tt.sigma, name, got, tt.relative[name])
+			}
+		}
+	}
+}
+
+func TestSearchHeading(t *testing.T) {
+	tests := []struct {
+		heading, want Heading
+	}{
+		{heading: Heading{}, want: Heading{Yaw: 180}},
+		{heading: Heading{Yaw: 90, Pitch: 10}, want: Heading{Yaw: 270, Pitch: -10}},
+		{heading: Heading{Yaw: 270, Pitch: -30}, want: Heading{Yaw: 90, Pitch: 30}},
+		{heading: Heading{Yaw: -90, Pitch: 89}, want: Heading{Yaw: 90, Pitch: -89}},
+	}
+	for _, tt := range tests {
+		forward := HeadingSearch{Heading: tt.heading}
+		reverse := HeadingSearch{Heading: tt.heading, Reverse: true}
+		if got := forward.searchHeading(); got != tt.heading {
+			t.Errorf("searchHeading() of %v = %v, want it unchanged", tt.heading, got)
+		}
+		if got := reverse.searchHeading(); math.Abs(got.Yaw-tt.want.Yaw) > 1e-9 || math.Abs(got.Pitch-tt.want.Pitch) > 1e-9 {
+			t.Errorf("reversed searchHeading() of %v = %v, want %v", tt.heading, got, tt.want)
+		}
+		// Reversing must point exactly the other way
+		if sum := forward.Direction().Add(reverse.Direction()); sum.Norm() > 1e-9 {
+			t.Errorf("directions for %v don't cancel, sum %v", tt.heading, sum)
+		}
+	}
+}
+
+func TestRankReverse(t *testing.T) {
+	climbing := Heading{Yaw: 30, Pitch: 20}
+	testNavComp(
+		bodyAt("going to", climbing, 300),
+		bodyAt("came from", Heading{Yaw: 210, Pitch: -20}, 300),
+		bodyAt("came from nearby", Heading{Yaw: 212, Pitch: -21}, 200),
+		// Behind in yaw but mirrored in pitch, 40 degrees off the track
+		bodyAt("behind and above", Heading{Yaw: 210, Pitch: 20}, 300),
+	)
+	search := HeadingSearch{Heading: climbing, Reverse: true, Distance: 1000, SDist: 1, MaxDeviation: 15, Sigma: 3, Speed: 8}
+	results, err := search.Rank()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, result := range results {
+		got = append(got, result.BodyOfInterest.Name)
+		if !result.Reverse || !strings.Contains(result.String(), " ago") {
+			t.Errorf("%s not shown as behind: %s", result.BodyOfInterest.Name, result)
+		}
+		if result.AlongTrack <= 0 {
+			t.Errorf("%s is %g along the reversed heading", result.BodyOfInterest.Name, result.AlongTrack)
+		}
+	}
+	if want := []string{"came from", "came from nearby"}; !sameNames(got, want) {
+		t.Errorf("Rank() found %v, want %v", got, want)
+	}
+	if results[0].Deviation > 1e-6 || math.Abs(results[0].YawOffset) > 1e-6 || math.Abs(results[0].PitchOffset) > 1e-6 {
+		t.Errorf("came from is %g off the track, yaw %g pitch %g", results[0].Deviation, results[0].YawOffset, results[0].PitchOffset)
+	}
+
+	search.Reverse = false
+	if results, err = search.Rank(); err != nil {
+		t.Fatal(err)
+	}
+	if got := resultNames(results); !sameNames(got, []string{"going to"}) || results[0].Reverse {
+		t.Errorf("forward Rank() found %v", got)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file gives every body a stable identifier and builds the table used
+// to look bodies up by ID, name or alias.  IDs come from the data when
+// present, otherwise they are derived from the empire and body names, e.g.
+// "federation/earth".  Writing the data back out keeps derived IDs, so a
+// later rename doesn't change them.
+//
+// Aliases can be listed on the body itself, or in the navcomp's alias table
+// keyed by ID:
+//
+//	"aliases": { "bajoran/deep-space-9": ["DS9", "Deep Space Nine", "Terok Nor"] }
+
+func slugify(s string) string {
+	var sb strings.Builder
+	lastDash := true
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			sb.WriteRune('-')
+			lastDash = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
+
+func DeriveBodyID(empire, name string) string {
+	return fmt.Sprintf("%s/%s", slugify(empire), slugify(name))
+}
+
+func lookupKey(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// assignIDs gives every body without an ID a derived one, adding a numeric
+// suffix when two bodies would derive the same ID.
+func (a *ATSData) assignIDs() {
+	taken := make(map[string]bool)
+	locations := a.bodyLocations()
+	for _, loc := range locations {
+		if loc.Body.ID != "" {
+			taken[loc.Body.ID] = true
+		}
+	}
+	for _, loc := range locations {
+		if loc.Body.ID != "" {
+			continue
+		}
+		base := DeriveBodyID(loc.Empire, loc.Body.Name)
+		id := base
+		for n := 2; taken[id]; n++ {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		loc.Body.ID = id
+		taken[id] = true
+	}
+}
+
+// buildLookup fills the ID and name tables.  IDs take priority over names,
+// and names over aliases, and the first body to claim a key keeps it.
+func (a *ATSData) buildLookup() {
+	a.byID = make(map[string]*AstralBody)
+	a.lookup = make(map[string]*AstralBody)
+	locations := a.bodyLocations()
+	for _, loc := range locations {
+		if _, ok := a.byID[loc.Body.ID]; !ok {
+			a.byID[loc.Body.ID] = loc.Body
+		}
+		if _, ok := a.lookup[lookupKey(loc.Body.ID)]; !ok {
+			a.lookup[lookupKey(loc.Body.ID)] = loc.Body
+		}
+	}
+	for _, loc := range locations {
+		if _, ok := a.lookup[lookupKey(loc.Body.Name)]; !ok {
+			a.lookup[lookupKey(loc.Body.Name)] = loc.Body
+		}
+	}
+	for _, loc := range locations {
+		loc.Body.TableAliases = nil
+	}
+	for id, aliases := range a.NavcompDB.Aliases {
+		body, ok := a.byID[id]
+		if !ok {
+			continue
+		}
+		body.TableAliases = appendMissing(body.TableAliases, aliases...)
+	}
+	for _, loc := range locations {
+		for _, alias := range loc.Body.AllAliases() {
+			if _, ok := a.lookup[lookupKey(alias)]; !ok {
+				a.lookup[lookupKey(alias)] = loc.Body
+			}
+		}
+	}
+}
+
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if strings.EqualFold(existing, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// FindByID returns the body with exactly the given ID.
+func (a *ATSData) FindByID(id string) (*AstralBody, bool) {
+	body, ok := a.byID[id]
+	return body, ok
+}
+
+// FindExact returns the body whose ID, name or alias matches exactly,
+// ignoring case.
+func (a *ATSData) FindExact(name string) (*AstralBody, bool) {
+	body, ok := a.lookup[lookupKey(name)]
+	return body, ok
+}
+
+// resolveGates fills Gates from GateNames, keyed by body ID.
+func (a *ATSData) resolveGates() {
+	Gates = make(map[string]*AstralBody)
+	for _, name := range GateNames {
+		if body, ok := a.FindExact(name); ok {
+			Gates[body.ID] = body
+		}
+	}
+}
+
+// assignOwners points every body back at its empire and records its kind.
+func (a *ATSData) assignOwners() {
+	for ndx := range a.NavcompDB.Empires {
+		empire := &a.NavcompDB.Empires[ndx]
+		for indx := range empire.Planets {
+			empire.Planets[indx].Kind = KIND_PLANETS
+			empire.Planets[indx].Empire = empire
+		}
+		for indx := range empire.Stations {
+			empire.Stations[indx].Kind = KIND_STATIONS
+			empire.Stations[indx].Empire = empire
+		}
+	}
+}
+
+// IndexBodies assigns IDs and owners and rebuilds every lookup table and
+// index.  It must be called again whenever bodies or empires are added or
+// removed.
+func (a *ATSData) IndexBodies() {
+	a.assignOwners()
+	a.assignIDs()
+	a.buildLookup()
+	a.resolveGates()
+	a.BuildIndex()
+}
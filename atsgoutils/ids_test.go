@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// parseTestData parses a single empire's planets and stations, plus an
+// optional alias table.
+func parseTestData(t *testing.T, planets, stations, aliases string) *ATSData {
+	t.Helper()
+	if aliases == "" {
+		aliases = "{}"
+	}
+	raw := `{"ATS_Navcomp_DB": {"version": 1, "aliases": ` + aliases + `, "empires": [{"name": "Bajoran", "borders": [], "planets": [` + planets + `], "stations": [` + stations + `]}]}}`
+	atsData, err := ParseATSData([]byte(raw), "atsdata.json")
+	if err != nil {
+		t.Fatalf("ParseATSData: %s", err)
+	}
+	return atsData
+}
+
+func TestDeriveBodyID(t *testing.T) {
+	tests := []struct {
+		empire, name, want string
+	}{
+		{empire: "Federation", name: "Earth", want: "federation/earth"},
+		{empire: "Bajoran", name: "Deep Space 9", want: "bajoran/deep-space-9"},
+		{empire: "Klingon", name: "IKB wej qogh puQmo'", want: "klingon/ikb-wej-qogh-puqmo"},
+		{empire: "Independent", name: "  RSB -- Rahoka!  ", want: "independent/rsb-rahoka"},
+		{empire: "", name: "", want: "/"},
+	}
+	for _, tt := range tests {
+		if got := DeriveBodyID(tt.empire, tt.name); got != tt.want {
+			t.Errorf("DeriveBodyID(%q, %q) = %q, want %q", tt.empire, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAssignIDs(t *testing.T) {
+	atsData := parseTestData(t,
+		`{"name": "Bajor", "x": 1, "y": 1, "z": 1}`,
+		`{"name": "Deep Space 9", "x": 2, "y": 2, "z": 2},
+		 {"name": "Deep Space 9", "x": 3, "y": 3, "z": 3},
+		 {"name": "Terok Nor", "id": "bajoran/deep-space-9-2", "x": 4, "y": 4, "z": 4}`,
+		"")
+	// The explicit ID is never handed out again, so the second Deep Space 9
+	// skips to -3
+	want := []string{"bajoran/bajor", "bajoran/deep-space-9", "bajoran/deep-space-9-3", "bajoran/deep-space-9-2"}
+	for ndx, loc := range atsData.bodyLocations() {
+		if loc.Body.ID != want[ndx] {
+			t.Errorf("%s has ID %q, want %q", loc, loc.Body.ID, want[ndx])
+		}
+		if body, ok := atsData.FindByID(want[ndx]); !ok || body != loc.Body {
+			t.Errorf("FindByID(%q) = %v, %v, want %s", want[ndx], body, ok, loc)
+		}
+	}
+}
+
+func TestFindExact(t *testing.T) {
+	atsData := parseTestData(t,
+		`{"name": "Bajor", "aliases": ["Deep Space 9", "home", "twin"], "x": 1, "y": 1, "z": 1},
+		 {"name": "bajoran/bajor", "x": 2, "y": 2, "z": 2},
+		 {"name": "Jeraddo", "aliases": ["TWIN"], "x": 3, "y": 3, "z": 3}`,
+		`{"name": "Deep Space 9", "x": 4, "y": 4, "z": 4}`,
+		`{"bajoran/deep-space-9": ["DS9", "Terok Nor"], "bajoran/jeraddo": ["home"], "bajoran/missing": ["Nowhere"]}`)
+	tests := []struct {
+		name, key, want string
+	}{
+		{name: "id", key: "BAJORAN/JERADDO", want: "Jeraddo"},
+		{name: "id before name", key: "bajoran/bajor", want: "Bajor"},
+		{name: "name before alias", key: "deep space 9", want: "Deep Space 9"},
+		{name: "alias on the body", key: " Twin ", want: "Bajor"},
+		{name: "alias from the table", key: "ds9", want: "Deep Space 9"},
+		{name: "first alias claim wins", key: "home", want: "Bajor"},
+		{name: "table entry without a body", key: "nowhere"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, ok := atsData.FindExact(tt.key)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("FindExact(%q) = %s, want no match", tt.key, body.Name)
+				}
+				return
+			}
+			if !ok || body.Name != tt.want {
+				t.Errorf("FindExact(%q) = %v, %v, want %s", tt.key, body, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteToFileKeepsAliasTable(t *testing.T) {
+	atsData := parseTestData(t, "",
+		`{"name": "Deep Space 9", "aliases": ["DS9"], "x": 1, "y": 1, "z": 1}`,
+		`{"bajoran/deep-space-9": ["Deep Space Nine", "Terok Nor"]}`)
+	ds9, _ := atsData.FindByID("bajoran/deep-space-9")
+	if got, want := ds9.AllAliases(), []string{"DS9", "Deep Space Nine", "Terok Nor"}; !sameNames(got, want) {
+		t.Errorf("AllAliases() = %q, want %q", got, want)
+	}
+
+	fname := filepath.Join(t.TempDir(), "atsdata.json")
+	// Writing twice must not move the table entries onto the body either
+	for round := 0; round < 2; round++ {
+		if err := atsData.WriteToFile(fname); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if atsData, err = ParseATSDataFromFile(fname); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written struct {
+		DB struct {
+			Empires []struct {
+				Stations []struct {
+					Aliases []string `json:"aliases"`
+				} `json:"stations"`
+			} `json:"empires"`
+			Aliases map[string][]string `json:"aliases"`
+		} `json:"ATS_Navcomp_DB"`
+	}
+	if err := json.Unmarshal(raw, &written); err != nil {
+		t.Fatal(err)
+	}
+	if got := written.DB.Empires[0].Stations[0].Aliases; !sameNames(got, []string{"DS9"}) {
+		t.Errorf("body written with aliases %q, want only its own", got)
+	}
+	if got := written.DB.Aliases["bajoran/deep-space-9"]; !sameNames(got, []string{"Deep Space Nine", "Terok Nor"}) {
+		t.Errorf("alias table written as %q", got)
+	}
+	if body, ok := atsData.FindExact("terok nor"); !ok || body.Name != "Deep Space 9" {
+		t.Errorf("table alias no longer resolves after writing, got %v, %v", body, ok)
+	}
+}
+
+func TestRouteCacheKeyedByID(t *testing.T) {
+	navComp := NavComp
+	t.Cleanup(func() { NavComp = navComp })
+	NavComp = parseTestData(t,
+		`{"name": "Bajor", "x": 0, "y": 0, "z": 0, "cochranes": 1000},
+		 {"name": "Bajor", "id": "bajoran/bajor-viii", "x": 50, "y": 0, "z": 0, "cochranes": 1000}`,
+		`{"name": "Deep Space 9", "x": 100, "y": 0, "z": 0, "cochranes": 1000}`,
+		"")
+	bajor, _ := NavComp.FindByID("bajoran/bajor")
+	bajor8, _ := NavComp.FindByID("bajoran/bajor-viii")
+	ds9, _ := NavComp.FindByID("bajoran/deep-space-9")
+
+	if got, want := GetRouteName(bajor, ds9), "bajoran/bajor to bajoran/deep-space-9"; got != want {
+		t.Errorf("GetRouteName() = %q, want %q", got, want)
+	}
+
+	cache := &RouteCache{Version: CACHE_VERSION, RouteMap: make(map[string]Route)}
+	if _, err := cache.GetRouteFromBodies(bajor, ds9); err != nil {
+		t.Fatal(err)
+	}
+	// The other Bajor shares a name but not an ID, so it mustn't hit
+	route, err := cache.GetRouteFromBodies(bajor8, ds9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.NumHits != 0 || cache.NumMisses != 2 || route.Distance != 50 {
+		t.Errorf("same named bodies: %d hits, %d misses, distance %.0f", cache.NumHits, cache.NumMisses, route.Distance)
+	}
+
+	// Round trip through a file, renaming the body in between
+	fname := filepath.Join(t.TempDir(), "cache.json")
+	if err := cache.WriteToFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	if cache, err = LoadCacheFromFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	bajor.Name = "Bajor Prime"
+	route, err = cache.GetRouteFromBodies(bajor, ds9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.NumHits != 1 {
+		t.Errorf("renamed body missed the cache")
+	}
+	if route.Source != bajor || route.Target != ds9 {
+		t.Errorf("cached route not relinked to the loaded bodies")
+	}
+
+	cache.Version = "1"
+	if err := cache.WriteToFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	if cache, err = LoadCacheFromFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Version != CACHE_VERSION || len(cache.RouteMap) != 0 {
+		t.Errorf("old cache version %q kept with %d routes", cache.Version, len(cache.RouteMap))
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	navComp := NavComp
+	t.Cleanup(func() { NavComp = navComp })
+	NavComp = &ATSData{Layers: []string{"/data/atsdata.json", "/data/extra.json"}}
+	tests := []struct {
+		name string
+		body AstralBody
+		want bool
+	}{
+		{name: "base data", body: AstralBody{Kind: KIND_PLANETS, Layer: "atsdata.json"}, want: true},
+		{name: "overlay", body: AstralBody{Kind: KIND_PLANETS, Layer: "extra.json"}},
+		{name: "bookmark", body: AstralBody{Kind: KIND_BOOKMARKS, Layer: "atsdata.json"}},
+	}
+	for _, tt := range tests {
+		if got := cacheable(&tt.body); got != tt.want {
+			t.Errorf("%s: cacheable() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAssignOwners(t *testing.T) {
+	atsData := loadTestNavComp(t)
+	tests := []struct {
+		name, empire, kind, label string
+	}{
+		{name: "Vulcan", empire: "Federation", kind: KIND_PLANETS, label: "Vulcan (Federation planet)"},
+		{name: "IKB wej qogh puQmo'", empire: "Klingon", kind: KIND_STATIONS, label: "IKB wej qogh puQmo' (Klingon station)"},
+	}
+	for _, tt := range tests {
+		body := findBody(t, atsData, tt.name)
+		if body.EmpireName() != tt.empire || body.Kind != tt.kind || body.Label() != tt.label {
+			t.Errorf("%s tagged %q %q %q, want %q %q %q", tt.name, body.EmpireName(), body.Kind, body.Label(), tt.empire, tt.kind, tt.label)
+		}
+	}
+
+	// Growing the empire list moves every empire, so the owners must be
+	// pointed at the new copies
+	atsData.NavcompDB.Empires = append(atsData.NavcompDB.Empires, Empire{Name: "Sheliak Corporate", Planets: []AstralBody{{Name: "Sheliak Homeworld", X: 1, Y: 2, Z: 3}}})
+	atsData.IndexBodies()
+	for _, loc := range atsData.bodyLocations() {
+		if loc.Body.Empire == nil || loc.Body.Empire.Name != loc.Empire || loc.Body.Kind != loc.Kind {
+			t.Fatalf("%s tagged %q %q after adding an empire", loc, loc.Body.EmpireName(), loc.Body.Kind)
+		}
+		if empire := atsData.findEmpire(loc.Empire); loc.Body.Empire != empire {
+			t.Fatalf("%s points at a stale copy of its empire", loc)
+		}
+	}
+
+	if label := (AstralBody{Name: "Nowhere"}).Label(); label != "Nowhere" {
+		t.Errorf("untagged body labelled %q", label)
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This file builds navcomp data from captured MUSH output rather than by
+// hand.  Two shapes of output are understood, and may be mixed in one log:
+//
+// Navcomp listings, one body per line after a section header:
+//
+//	--- Federation ---
+//	Planets:
+//	Alpha Centauri      -9173.253   62.558   -1.032   1509
+//	Borders:
+//	Federation          -9197.944    0.000    0.000    240
+//
+// Scan reports, as blocks of "Key: value" lines:
+//
+//	Name: Andor
+//	Type: Planet
+//	Empire: Federation
+//	Coordinates: -9172.144 60.100 -2.400
+//	Cochranes: 1501
+//
+// The last number on a listing line is Cochranes for planets and stations
+// and the radius for borders.
+
+var (
+	importTimestampRe = regexp.MustCompile(`^\[\d{1,2}:\d{2}(?::\d{2})?\]\s*`)
+	importEmpireRe    = regexp.MustCompile(`^(?:-{2,}|={2,})\s*(.+?)\s*(?:-{2,}|={2,})$`)
+	importSectionRe   = regexp.MustCompile(`(?i)^(planets?|stations?|borders?)\s*:?$`)
+	importKeyValueRe  = regexp.MustCompile(`(?i)^(name|type|empire|space|coordinates|coords|location|position|cochranes|cochrane density|radius)\s*:\s*(.*)$`)
+	importListingRe   = regexp.MustCompile(`^(.+?)\s+(-?\d+(?:\.\d+)?)\s+(-?\d+(?:\.\d+)?)\s+(-?\d+(?:\.\d+)?)(?:\s+(\d+(?:\.\d+)?))?$`)
+	importNumberRe    = regexp.MustCompile(`-?\d+(?:\.\d+)?`)
+)
+
+func normaliseKind(kind string) (string, bool) {
+	switch strings.TrimSuffix(strings.ToLower(strings.TrimSpace(kind)), "s") {
+	case "planet":
+		return KIND_PLANETS, true
+	case "station":
+		return KIND_STATIONS, true
+	case "border":
+		return KIND_BORDERS, true
+	}
+	return "", false
+}
+
+type ImportRecord struct {
+	Source    string
+	Kind      string
+	Empire    string
+	Name      string
+	X, Y, Z   float64
+	HasCoords bool
+	Cochranes float64
+	Radius    float64
+}
+
+func (r ImportRecord) String() string {
+	return fmt.Sprintf("%s: %s %q", r.Source, strings.TrimSuffix(r.Kind, "s"), r.Name)
+}
+
+func (r ImportRecord) Point() Point {
+	return Point{X: r.X, Y: r.Y, Z: r.Z}
+}
+
+// MUSHLogParser accumulates records from one or more captured logs.  Kind is
+// used for scan reports with no Type.  Listing lines are only read after a
+// section header, other lines there are reported as skipped.
+type MUSHLogParser struct {
+	Kind     string
+	Records  []ImportRecord
+	Warnings []string
+	empire   string
+	kind     string
+	pending  *ImportRecord
+}
+
+func (p *MUSHLogParser) warnf(source, format string, a ...any) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf("%s: %s", source, fmt.Sprintf(format, a...)))
+}
+
+func (p *MUSHLogParser) flush() {
+	if p.pending == nil {
+		return
+	}
+	record := *p.pending
+	p.pending = nil
+	if !record.HasCoords {
+		p.warnf(record.Source, "%q has no coordinates, skipping", record.Name)
+		return
+	}
+	p.Records = append(p.Records, record)
+}
+
+func (p *MUSHLogParser) parseKeyValue(source, key, value string) {
+	key = strings.ToLower(key)
+	if key == "name" {
+		p.flush()
+		p.pending = &ImportRecord{Source: source, Kind: p.Kind, Name: value}
+		return
+	}
+	if p.pending == nil {
+		if key == "empire" || key == "space" {
+			p.empire = value
+		} else {
+			p.warnf(source, "%s outside of a scan report, ignoring", key)
+		}
+		return
+	}
+	numbers := importNumberRe.FindAllString(value, -1)
+	switch key {
+	case "type":
+		kind, ok := normaliseKind(value)
+		if !ok {
+			p.warnf(source, "unknown type %q", value)
+			return
+		}
+		p.pending.Kind = kind
+	case "empire", "space":
+		p.pending.Empire = value
+	case "cochranes", "cochrane density":
+		if len(numbers) > 0 {
+			p.pending.Cochranes, _ = strconv.ParseFloat(numbers[0], 64)
+		}
+	case "radius":
+		if len(numbers) > 0 {
+			p.pending.Radius, _ = strconv.ParseFloat(numbers[0], 64)
+		}
+	default:
+		if len(numbers) < 3 {
+			p.warnf(source, "expected 3 coordinates, received %q", value)
+			return
+		}
+		p.pending.X, _ = strconv.ParseFloat(numbers[0], 64)
+		p.pending.Y, _ = strconv.ParseFloat(numbers[1], 64)
+		p.pending.Z, _ = strconv.ParseFloat(numbers[2], 64)
+		p.pending.HasCoords = true
+	}
+}
+
+func (p *MUSHLogParser) Parse(name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		source := fmt.Sprintf("%s:%d", name, lineNum)
+		line := strings.TrimSpace(importTimestampRe.ReplaceAllString(scanner.Text(), ""))
+		if line == "" {
+			p.flush()
+			continue
+		}
+		if match := importKeyValueRe.FindStringSubmatch(line); match != nil {
+			p.parseKeyValue(source, match[1], strings.TrimSpace(match[2]))
+			continue
+		}
+		p.flush()
+		if match := importEmpireRe.FindStringSubmatch(line); match != nil {
+			p.empire = match[1]
+			continue
+		}
+		if match := importSectionRe.FindStringSubmatch(line); match != nil {
+			p.kind, _ = normaliseKind(match[1])
+			continue
+		}
+		match := importListingRe.FindStringSubmatch(line)
+		if match == nil {
+			if p.kind != "" {
+				p.warnf(source, "skipping %q, not a listing line", line)
+			}
+			continue
+		}
+		if p.kind == "" {
+			p.warnf(source, "skipping %q, listing lines must follow a Planets:, Stations: or Borders: header", line)
+			continue
+		}
+		record := ImportRecord{Source: source, Kind: p.kind, Empire: p.empire, Name: match[1], HasCoords: true}
+		record.X, _ = strconv.ParseFloat(match[2], 64)
+		record.Y, _ = strconv.ParseFloat(match[3], 64)
+		record.Z, _ = strconv.ParseFloat(match[4], 64)
+		if match[5] != "" {
+			last, _ := strconv.ParseFloat(match[5], 64)
+			if record.Kind == KIND_BORDERS {
+				record.Radius = last
+			} else {
+				record.Cochranes = last
+			}
+		}
+		p.Records = append(p.Records, record)
+	}
+	p.flush()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return nil
+}
+
+type ImportConflict struct {
+	Record ImportRecord
+	Reason string
+}
+
+func (c ImportConflict) String() string {
+	return fmt.Sprintf("%s: %s", c.Record, c.Reason)
+}
+
+type ImportResult struct {
+	Added, Updated, Unchanged int
+	Conflicts                 []ImportConflict
+}
+
+// findEmpire looks up an empire by case-insensitive name.
+func (a *ATSData) findEmpire(name string) *Empire {
+	for ndx := range a.NavcompDB.Empires {
+		if strings.EqualFold(a.NavcompDB.Empires[ndx].Name, name) {
+			return &a.NavcompDB.Empires[ndx]
+		}
+	}
+	return nil
+}
+
+func (a *ATSData) findOrAddEmpire(name string) *Empire {
+	if empire := a.findEmpire(name); empire != nil {
+		return empire
+	}
+	a.NavcompDB.Empires = append(a.NavcompDB.Empires, Empire{Name: name})
+	return &a.NavcompDB.Empires[len(a.NavcompDB.Empires)-1]
+}
+
+// importDifferences describes how a record disagrees with what is already in
+// the data, ignoring Cochranes and radius when the record doesn't have them.
+func importDifferences(record ImportRecord, existing Point, cochranes, radius, tolerance float64) []string {
+	var reasons []string
+	if shift := existing.Distance(record.Point()); shift > tolerance {
+		reasons = append(reasons, fmt.Sprintf("coordinates differ by %.3f (%.3f, %.3f, %.3f) -> (%.3f, %.3f, %.3f)", shift, existing.X, existing.Y, existing.Z, record.X, record.Y, record.Z))
+	}
+	if record.Cochranes != 0 && math.Abs(record.Cochranes-cochranes) > DIFF_EPSILON {
+		reasons = append(reasons, fmt.Sprintf("cochranes differ %.3f -> %.3f", cochranes, record.Cochranes))
+	}
+	if record.Radius != 0 && math.Abs(record.Radius-radius) > DIFF_EPSILON {
+		reasons = append(reasons, fmt.Sprintf("radius differs %.2f -> %.2f", radius, record.Radius))
+	}
+	return reasons
+}
+
+func (a *ATSData) mergeBorder(record ImportRecord, defaultEmpire string, tolerance float64, overwrite bool, result *ImportResult) {
+	empireName := record.Empire
+	if empireName == "" {
+		empireName = defaultEmpire
+	}
+	empire := a.findOrAddEmpire(empireName)
+	for ndx := range empire.Borders {
+		border := &empire.Borders[ndx]
+		if !strings.EqualFold(border.Name, record.Name) {
+			continue
+		}
+		reasons := importDifferences(record, Point{X: border.X, Y: border.Y, Z: border.Z}, 0, border.Radius, tolerance)
+		switch {
+		case len(reasons) == 0:
+			result.Unchanged++
+		case overwrite:
+			border.X, border.Y, border.Z = record.X, record.Y, record.Z
+			if record.Radius != 0 {
+				border.Radius = record.Radius
+			}
+			border.CreatePoint()
+			result.Updated++
+		default:
+			result.Conflicts = append(result.Conflicts, ImportConflict{Record: record, Reason: strings.Join(reasons, ", ")})
+		}
+		return
+	}
+	border := Border{Name: record.Name, X: record.X, Y: record.Y, Z: record.Z, Radius: record.Radius}
+	border.CreatePoint()
+	empire.Borders = append(empire.Borders, border)
+	result.Added++
+}
+
+func (a *ATSData) mergeBody(record ImportRecord, defaultEmpire string, tolerance float64, overwrite bool, result *ImportResult) {
+	for _, loc := range a.bodyLocations() {
+		if !strings.EqualFold(loc.Body.Name, record.Name) {
+			continue
+		}
+		body := loc.Body
+		reasons := importDifferences(record, Point{X: body.X, Y: body.Y, Z: body.Z}, body.Cochranes, 0, tolerance)
+		if record.Empire != "" && !strings.EqualFold(record.Empire, loc.Empire) {
+			reasons = append(reasons, fmt.Sprintf("already listed under %s, not %s", loc.Empire, record.Empire))
+		}
+		if record.Kind != loc.Kind {
+			reasons = append(reasons, fmt.Sprintf("already listed as a %s", strings.TrimSuffix(loc.Kind, "s")))
+		}
+		switch {
+		case len(reasons) == 0:
+			result.Unchanged++
+		case overwrite:
+			// Only the measurements are overwritten, moving a body between
+			// empires or lists is left to a human
+			body.X, body.Y, body.Z = record.X, record.Y, record.Z
+			if record.Cochranes != 0 {
+				body.Cochranes = record.Cochranes
+			}
+			body.CreatePoint()
+			result.Updated++
+		default:
+			result.Conflicts = append(result.Conflicts, ImportConflict{Record: record, Reason: strings.Join(reasons, ", ")})
+		}
+		return
+	}
+	empireName := record.Empire
+	if empireName == "" {
+		empireName = defaultEmpire
+	}
+	empire := a.findOrAddEmpire(empireName)
+	// Market is unknown from a scan, -1 is what the data uses for none
+	body := AstralBody{Name: record.Name, X: record.X, Y: record.Y, Z: record.Z, Cochranes: record.Cochranes, Market: -1}
+	body.CreatePoint()
+	if record.Kind == KIND_STATIONS {
+		empire.Stations = append(empire.Stations, body)
+	} else {
+		empire.Planets = append(empire.Planets, body)
+	}
+	result.Added++
+}
+
+// MergeImport adds imported records to the data.  Records that disagree with
+// an existing body or border by more than tolerance parsecs (or in
+// Cochranes/radius) are reported as conflicts and left alone unless
+// overwrite is set.
+func (a *ATSData) MergeImport(records []ImportRecord, defaultEmpire string, tolerance float64, overwrite bool) ImportResult {
+	var result ImportResult
+	for _, record := range records {
+		if record.Kind == KIND_BORDERS {
+			a.mergeBorder(record, defaultEmpire, tolerance, overwrite, &result)
+		} else {
+			a.mergeBody(record, defaultEmpire, tolerance, overwrite, &result)
+		}
+	}
+	return result
+}
+
+func runImport(cfg *Config, args []string) error {
+	importCmd := flag.NewFlagSet("import", flag.ContinueOnError)
+	importEmpire := importCmd.String("empire", "Independent", "Empire for records with no empire header")
+	importKind := importCmd.String("kind", KIND_PLANETS, "Kind for scan reports with no Type: planets, stations or borders")
+	importTolerance := importCmd.Float64("tolerance", 0.01, "Coordinate difference in parsecs before a record conflicts with existing data")
+	importOverwrite := importCmd.Bool("overwrite", false, "Overwrite existing bodies and borders instead of reporting conflicts")
+	importOutput := importCmd.String("o", "", "File to write the merged data to, stdout by default")
+	importNew := importCmd.Bool("new", false, "Start from empty data instead of merging into the existing data file")
+	cfg.AddFlags(importCmd)
+	importCmd.Usage = func() {
+		fmt.Fprintln(importCmd.Output(), "Usage: import [flags] [log files...]\nReads stdin when no log files are given.")
+		importCmd.PrintDefaults()
+	}
+	if err := parseFlags(importCmd, args); err != nil {
+		return err
+	}
+	kind, ok := normaliseKind(*importKind)
+	if !ok {
+		return usageErrorf("unknown kind %q, expected planets, stations or borders", *importKind)
+	}
+	parser := &MUSHLogParser{Kind: kind}
+	if importCmd.NArg() == 0 {
+		if err := parser.Parse("stdin", os.Stdin); err != nil {
+			return err
+		}
+	}
+	for _, fname := range importCmd.Args() {
+		f, err := os.Open(fname)
+		if err != nil {
+			return fmt.Errorf("unable to open %s: %w", fname, err)
+		}
+		err = parser.Parse(fname, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for _, warning := range parser.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	atsData := &ATSData{}
+	if !*importNew {
+		// Overlays are private, so only the data file is merged into
+		base, err := cfg.LoadBaseData()
+		if err != nil {
+			return fmt.Errorf("%w, or -new to start from empty data", err)
+		}
+		atsData = base
+	}
+	result := atsData.MergeImport(parser.Records, *importEmpire, *importTolerance, *importOverwrite)
+	for _, conflict := range result.Conflicts {
+		log.Printf("Conflict: %s", conflict)
+	}
+	log.Printf("Imported %d records: %d added, %d updated, %d unchanged, %d conflicts", len(parser.Records), result.Added, result.Updated, result.Unchanged, len(result.Conflicts))
+
+	if *importOutput == "" {
+		rawBytes, err := json.MarshalIndent(atsData, "", "\t")
+		if err != nil {
+			return fmt.Errorf("error marshalling json: %w", err)
+		}
+		fmt.Println(string(rawBytes))
+	} else if err := atsData.WriteToFile(*importOutput); err != nil {
+		return err
+	}
+	if len(result.Conflicts) > 0 {
+		return fmt.Errorf("%d conflicting records were not imported, use -overwrite to replace existing data", len(result.Conflicts))
+	}
+	return nil
+}
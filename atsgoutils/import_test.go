@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestImportIgnoresOverlays(t *testing.T) {
+	overlay := writeTestFile(t, "overlay.json", `{"ATS_Navcomp_DB": {"empires": [
+		{"name": "Federation", "planets": [{"name": "Vulcan", "hide": true}], "stations": [{"name": "Secret Outpost", "x": 1, "y": 2, "z": 3}]}
+	]}}`)
+	logFile := writeTestFile(t, "log.txt", "Name: Nowhere Station\nType: Station\nEmpire: Independent\nCoordinates: 10 20 30\n")
+	output := filepath.Join(t.TempDir(), "out.json")
+
+	cfg := &Config{DataPath: "atsdata.json", Overlays: stringList{overlay}}
+	if err := runImport(cfg, []string{"-o", output, logFile}); err != nil {
+		t.Fatalf("runImport: %s", err)
+	}
+	rawBytes, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := string(rawBytes)
+	if strings.Contains(written, "Secret Outpost") {
+		t.Errorf("overlay body Secret Outpost was written to the import output")
+	}
+	if !strings.Contains(written, `"Vulcan"`) {
+		t.Errorf("Vulcan, hidden by the overlay, is missing from the import output")
+	}
+	if !strings.Contains(written, "Nowhere Station") {
+		t.Errorf("imported body Nowhere Station is missing from the import output")
+	}
+}
+
+func TestMUSHLogParser(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		// want lists the records as "kind empire/name x y z cochranes radius"
+		want     []string
+		warnings int
+	}{
+		{
+			name: "listing sections",
+			log: `--- Federation ---
+Planets:
+Alpha Centauri      -9173.253   62.558   -1.032   1509
+Stations:
+[12:01] Earth Station  -9197.9 0 0
+Borders:
+Federation          -9197.944    0.000    0.000    240
+`,
+			want: []string{
+				"planets Federation/Alpha Centauri -9173.253 62.558 -1.032 1509 0",
+				"stations Federation/Earth Station -9197.9 0 0 0 0",
+				"borders Federation/Federation -9197.944 0 0 0 240",
+			},
+		},
+		{
+			name:     "listing without a section",
+			log:      "Meeting at 10 20 30\n",
+			warnings: 1,
+		},
+		{
+			name:     "chatter inside a section",
+			log:      "Planets:\nVulcan -9185.8 19.8 -1.9 1501\nSomeone says, \"hello\"\n",
+			want:     []string{"planets /Vulcan -9185.8 19.8 -1.9 1501 0"},
+			warnings: 1,
+		},
+		{
+			name: "scan reports",
+			log: `Name: Andor
+Type: Planet
+Empire: Federation
+Coordinates: -9172.144 60.100 -2.400
+Cochranes: 1501
+
+Name: Deep Space Nine
+Coordinates: 1 2 3
+Name: Nowhere
+`,
+			want: []string{
+				"planets Federation/Andor -9172.144 60.1 -2.4 1501 0",
+				"stations /Deep Space Nine 1 2 3 0 0",
+			},
+			warnings: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &MUSHLogParser{Kind: KIND_STATIONS}
+			if err := parser.Parse("log", strings.NewReader(tt.log)); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range parser.Records {
+				got = append(got, fmt.Sprintf("%s %s/%s %g %g %g %g %g", r.Kind, r.Empire, r.Name, r.X, r.Y, r.Z, r.Cochranes, r.Radius))
+			}
+			if !sameNames(got, tt.want) {
+				t.Errorf("got records %q, want %q", got, tt.want)
+			}
+			if len(parser.Warnings) != tt.warnings {
+				t.Errorf("got warnings %q, want %d", parser.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestImportNeedsADataFile(t *testing.T) {
+	empty := t.TempDir()
+	t.Setenv(DATA_ENV_VAR, "")
+	t.Setenv("XDG_DATA_HOME", empty)
+	t.Setenv("XDG_DATA_DIRS", empty)
+	chdir(t, empty)
+	logFile := writeTestFile(t, "log.txt", "Name: Nowhere Station\nType: Station\nCoordinates: 10 20 30\n")
+	output := filepath.Join(empty, "out.json")
+
+	if err := runImport(&Config{}, []string{"-o", output, logFile}); err == nil || !strings.Contains(err.Error(), "-new") {
+		t.Errorf("importing with no data file gave %v, want an error suggesting -new", err)
+	}
+	if _, err := os.Stat(output); err == nil {
+		t.Errorf("%s was written from the embedded data", output)
+	}
+	if err := runImport(&Config{}, []string{"-new", "-o", output, logFile}); err != nil {
+		t.Errorf("importing with -new: %s", err)
+	}
+	if err := runValidate(&Config{}, nil); err == nil {
+		t.Errorf("validating with no data file passed, want an error rather than the embedded data")
+	}
+}
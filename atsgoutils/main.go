@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -11,118 +12,190 @@ var (
 	NavComp *ATSData
 )
 
-func init() {
-	atsDataFilename := "./atsdata.json"
-	atsData, err := ParseATSDataFromFile(atsDataFilename)
+const SUBCOMMANDS = "bestroute, crossings, data, diff, export, findheading, import, market, ono, origin, parse-alert, territory, track, trade, or validate"
+
+func findHeading(contact Contact, search HeadingSearch, numResults int) error {
+	source, err := contact.GRC()
 	if err != nil {
-		log.Printf("Error parseing ATS Data from file %s: %s", atsDataFilename, err)
-		panic(err)
+		return err
 	}
-	NavComp = atsData
-	log.Printf("NavComp Loaded")
-	r, err := LoadCacheFromFile("atscache.json")
+	search.Source = source
+	search.Heading = contact.Heading
+	search.Speed = contact.Speed
+	headings, err := search.Rank()
 	if err != nil {
-		log.Printf("Error loading cache from file %s: %s", "atscache.json", err)
-		panic(err)
+		return fmt.Errorf("error during findobject: %w", err)
 	}
-	routeCache = r
-	log.Println("Route Cache Loaded")
+	PrintHeadingResults(headings, numResults)
+	return nil
+}
 
+// parseFlags parses a subcommand's flags, marking anything other than a
+// request for help as a usage error.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	return err
 }
 
-func findHeading(x, y, z, pitch, yaw, speed, lineDistance, sdist *float64, empire *string) error {
-	if (x != nil && *x <= -99999) || (y != nil && *y <= -99999) || (z != nil && *z <= -99999) {
-		return fmt.Errorf("expected x, y and z, received %f, %f and %f respectively", *x, *y, *z)
+func runBestRoute(cfg *Config, args []string) error {
+	brouteCmd := flag.NewFlagSet("bestroute", flag.ContinueOnError)
+	brouteSource := brouteCmd.String("source", "", "Source Object Name or Partial name (e.g. magna for Magna Roma)")
+	brouteTarget := brouteCmd.String("target", "", "Target Object Name or Partial name (e.g. 303 for 303)")
+	brouteSpeed := brouteCmd.Float64("speed", 22, "Speed in knots")
+	brouteExact := brouteCmd.Bool("exact", false, "Only accept exact IDs, names or aliases")
+	cfg.AddFlags(brouteCmd)
+	if err := parseFlags(brouteCmd, args); err != nil {
+		return err
 	}
-	if (pitch != nil && *pitch >= 400) || (yaw != nil && *yaw >= 400) {
-		return fmt.Errorf("expected pitch and yaw, received %f and %f respectively", *pitch, *yaw)
+	if *brouteSource == "" || *brouteTarget == "" {
+		return usageErrorf("expected 'source' and 'target' flags")
 	}
-	if speed != nil && *speed == 999 {
-		return fmt.Errorf("expected speed, received %f", *speed)
+	if err := cfg.Load(); err != nil {
+		return err
 	}
-	headings, err := FindObjectAlongLine(*x, *y, *z, *yaw, *pitch, *speed, *lineDistance, *sdist, empire)
+	source, err := cfg.ResolveBody(*brouteSource, *brouteExact)
 	if err != nil {
-		return fmt.Errorf("error during findobject: %w", err)
+		return fmt.Errorf("error looking up source %s: %w", *brouteSource, err)
 	}
-	for _, heading := range headings {
-		fmt.Println(heading)
+	target, err := cfg.ResolveBody(*brouteTarget, *brouteExact)
+	if err != nil {
+		return fmt.Errorf("error looking up target %s: %w", *brouteTarget, err)
+	}
+	route, err := BestRoute(source, target)
+	if err != nil {
+		return fmt.Errorf("error calculating best route: %w", err)
 	}
+	_, statement := route.GetStatement(*brouteSpeed)
+	fmt.Println(statement)
 	return nil
 }
 
-func main() {
-	brouteCmd := flag.NewFlagSet("bestroute", flag.ExitOnError)
-	brouteSource := brouteCmd.String("source", "", "Source Object Name or Partial name (e.g. magna for Magna Roma)")
-	brouteTarget := brouteCmd.String("target", "", "Target Object Name or Partial name (e.g. 303 for 303)")
-	brouteSpeed := brouteCmd.Float64("speed", 22, "Speed in knots")
-	findHeadingCmd := flag.NewFlagSet("findheading", flag.ExitOnError)
-	findHeadingX := findHeadingCmd.Float64("X", -99999, "X Coordinate")
-	findHeadingY := findHeadingCmd.Float64("Y", -99999, "X Coordinate")
-	findHeadingZ := findHeadingCmd.Float64("Z", -99999, "X Coordinate")
-	findHeadingSpeed := findHeadingCmd.Float64("speed", 999, "Warp Speed the object was travelling at")
-	findHeadingPitch := findHeadingCmd.Float64("pitch", 999, "Pitch of the object")
-	findHeadingYaw := findHeadingCmd.Float64("yaw", 999, "Yaw of the object")
-	findHeadingEmpire := findHeadingCmd.String("frame", "grc", "Empire, by default, this is GRC")
-	findHeadingSDist := findHeadingCmd.Float64("sdist", 50, "Distance to use as the 'Same Object', you don't get the station -and- planet")
-	findHeadingLineDist := findHeadingCmd.Float64("dist", 1000, "Length of virtual line to use in Parsecs")
-	onoCmd := flag.NewFlagSet("ono", flag.ExitOnError)
+func runFindHeading(cfg *Config, args []string) error {
+	return runHeadingSearch(cfg, "findheading", false, args)
+}
+
+// runOrigin is findheading -reverse.
+func runOrigin(cfg *Config, args []string) error {
+	return runHeadingSearch(cfg, "origin", true, args)
+}
+
+func runHeadingSearch(cfg *Config, name string, reverse bool, args []string) error {
+	findHeadingCmd := flag.NewFlagSet(name, flag.ContinueOnError)
+	findHeadingContact := AddContactFlags(findHeadingCmd)
+	findHeadingSearch := AddHeadingSearchFlags(findHeadingCmd)
+	findHeadingNumResults := findHeadingCmd.Int("num-results", 10, "Number of results to display")
+	findHeadingReverse := findHeadingCmd.Bool("reverse", reverse, "Search behind the contact for where it came from")
+	cfg.AddFlags(findHeadingCmd)
+	if err := parseFlags(findHeadingCmd, args); err != nil {
+		return err
+	}
+	contact, err := findHeadingContact(cfg.Angles)
+	if err != nil {
+		return err
+	}
+	search, err := findHeadingSearch()
+	if err != nil {
+		return err
+	}
+	search.Reverse = *findHeadingReverse
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+	err = findHeading(contact, search, *findHeadingNumResults)
+	if err != nil {
+		return fmt.Errorf("error finding heading: %w", err)
+	}
+	return nil
+}
+
+func runOno(cfg *Config, args []string) error {
+	onoCmd := flag.NewFlagSet("ono", flag.ContinueOnError)
 	onoSource := onoCmd.String("source", "", "Source Object to determine objects nearby")
 	onoRange := onoCmd.Float64("range", 200, "Range of objects to consider")
 	onoNumResults := onoCmd.Int("num-results", 20, "Number of results to display")
-	if len(os.Args) < 2 {
-		log.Println("Expected subcommand of bestroute, findheading, or ono")
-		os.Exit(1)
+	onoExact := onoCmd.Bool("exact", false, "Only accept an exact ID, name or alias")
+	onoFilter := AddBodyFilterFlags(onoCmd)
+	cfg.AddFlags(onoCmd)
+	if err := parseFlags(onoCmd, args); err != nil {
+		return err
+	}
+	filter, err := onoFilter()
+	if err != nil {
+		return err
 	}
-	switch os.Args[1] {
+	if *onoSource == "" {
+		return usageErrorf("source is required, none supplied")
+	}
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+	sourceObject, err := cfg.ResolveBody(*onoSource, *onoExact)
+	if err != nil {
+		return fmt.Errorf("cannot locate object from string %s: %w", *onoSource, err)
+	}
+	getNearbyObjects(sourceObject, onoRange, onoNumResults, filter.Matches)
+	return nil
+}
+
+func run(args []string) error {
+	cfg := &Config{}
+	globalCmd := flag.NewFlagSet(APP_NAME, flag.ContinueOnError)
+	cfg.AddFlags(globalCmd)
+	globalCmd.Usage = func() {
+		fmt.Fprintf(globalCmd.Output(), "Usage: %s [--data file] [--cache file] [--market file] [--output-frame frame] [--angle-units deg|rad] [--yaw-convention math|compass] [--pitch-convention up|down] [--overlay file]... [--config file] [--non-interactive] <subcommand> [flags]\n\nSubcommands: %s\n\n", APP_NAME, SUBCOMMANDS)
+		globalCmd.PrintDefaults()
+	}
+	if err := parseFlags(globalCmd, args); err != nil {
+		return err
+	}
+	if globalCmd.NArg() < 1 {
+		globalCmd.Usage()
+		return usageErrorf("expected subcommand of %s", SUBCOMMANDS)
+	}
+	subArgs := globalCmd.Args()[1:]
+	switch globalCmd.Arg(0) {
 	case "bestroute":
-		brouteCmd.Parse(os.Args[2:])
-		if *brouteSource == "" || *brouteTarget == "" {
-			log.Println("Expected 'source' and 'target' flags")
-			os.Exit(1)
-		}
-		source, target, err := NavComp.ResolveObjects(*brouteSource, *brouteTarget)
-		if err != nil {
-			log.Printf("Error resolving objects: %s", err)
-			panic(err)
-		}
-		route, err := BestRoute(source, target)
-		if err != nil {
-			log.Printf("Error calculating best route: %s", err)
-			panic(err)
-		}
-		_, statement := route.GetStatement(*brouteSpeed)
-		fmt.Println(statement)
-		// duration := route.TimeToExecute(*brouteSpeed)
-		// if err != nil {
-		// 	log.Printf("Error calculating best route: %s", err)
-		// 	panic(err)
-		// }
-		// log.Printf("It should take %s to go from %s to %s at %f", duration, *brouteSource, *brouteTarget, *brouteSpeed)
+		return runBestRoute(cfg, subArgs)
+	case "crossings":
+		return runCrossings(cfg, subArgs)
+	case "data":
+		return runData(cfg, subArgs)
+	case "diff":
+		return runDiff(subArgs)
+	case "export":
+		return runExport(cfg, subArgs)
 	case "findheading":
-		findHeadingCmd.Parse(os.Args[2:])
-		err := findHeading(findHeadingX, findHeadingY, findHeadingZ, findHeadingPitch, findHeadingYaw, findHeadingSpeed, findHeadingLineDist, findHeadingSDist, findHeadingEmpire)
-		if err != nil {
-			log.Printf("Error finding Heading: %s", err)
-			panic(err)
-		}
+		return runFindHeading(cfg, subArgs)
+	case "import":
+		return runImport(cfg, subArgs)
+	case "market":
+		return runMarket(cfg, subArgs)
 	case "ono":
-		onoCmd.Parse(os.Args[2:])
-		if *onoSource == "" {
-			log.Println("Source is required, none supplied")
-			os.Exit(1)
-		}
-		sourceObject, err := NavComp.FindObject(*onoSource)
-		if err != nil {
-			log.Printf("Cannot locate object from string %s: %s", *onoSource, err)
-			os.Exit(1)
-		}
-		getNearbyObjects(sourceObject, onoRange, onoNumResults)
-		if err != nil {
-			log.Printf("Unable to find nearby objects: %s", err)
-			os.Exit(1)
-		}
+		return runOno(cfg, subArgs)
+	case "origin":
+		return runOrigin(cfg, subArgs)
+	case "parse-alert":
+		return runParseAlert(cfg, subArgs)
+	case "territory":
+		return runTerritory(cfg, subArgs)
+	case "track":
+		return runTrack(cfg, subArgs)
+	case "trade":
+		return runTrade(cfg, subArgs)
+	case "validate":
+		return runValidate(cfg, subArgs)
 	default:
-		log.Println("Expected subcommand of bestroute, findheading, or ono")
-		os.Exit(1)
+		return usageErrorf("unknown subcommand %q, expected subcommand of %s", globalCmd.Arg(0), SUBCOMMANDS)
+	}
+}
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		log.Printf("Error: %s", err)
 	}
+	os.Exit(exitCode(err))
 }
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+)
+
+// This file models the markets found at bodies.  AstralBody.Market is the
+// body's market tier, MARKET_NONE for bodies without one.  A separate market
+// file names the tiers, lists the commodities each tier trades and gives
+// base prices, with optional per body prices overriding them:
+//
+//	{
+//		"version": 1,
+//		"tiers": [{"tier": 2, "name": "Standard", "commodities": ["Dilithium"], "buy_factor": 1.1, "sell_factor": 0.9}],
+//		"commodities": [{"name": "Dilithium", "base_price": 120}],
+//		"markets": [{"body": "federation/earth", "prices": {"Dilithium": {"buy": 110, "sell": 100}}}]
+//	}
+
+const MARKET_NONE = -1
+
+var (
+	Markets *MarketDB
+)
+
+type MarketTier struct {
+	Tier        int64    `json:"tier"`
+	Name        string   `json:"name"`
+	Commodities []string `json:"commodities"`
+	// BuyFactor and SellFactor scale a commodity's base price to what the
+	// market charges and what it pays, both default to 1
+	BuyFactor  float64 `json:"buy_factor,omitempty"`
+	SellFactor float64 `json:"sell_factor,omitempty"`
+}
+
+type Commodity struct {
+	Name      string  `json:"name"`
+	BasePrice float64 `json:"base_price"`
+}
+
+// Price is what a market charges to Buy a unit of a commodity and what it
+// pays to Sell one there.  A zero price means it isn't traded that way.
+type Price struct {
+	Buy  float64 `json:"buy,omitempty"`
+	Sell float64 `json:"sell,omitempty"`
+}
+
+type BodyMarket struct {
+	// Body is an ID, name or alias
+	Body   string           `json:"body"`
+	Prices map[string]Price `json:"prices"`
+}
+
+type MarketDB struct {
+	Version     float64      `json:"version"`
+	Tiers       []MarketTier `json:"tiers"`
+	Commodities []Commodity  `json:"commodities"`
+	Markets     []BodyMarket `json:"markets"`
+	byBody      map[string]*BodyMarket
+}
+
+func ParseMarketDBFromFile(filename string) (*MarketDB, error) {
+	var m MarketDB
+	rawBytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", filename, err)
+	}
+	err = json.Unmarshal(rawBytes, &m)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling json: %w", err)
+	}
+	return &m, nil
+}
+
+// Link resolves each per body market against the navcomp, returning a
+// warning for every body or commodity it doesn't know.
+func (m *MarketDB) Link(a *ATSData) []string {
+	var warnings []string
+	m.byBody = make(map[string]*BodyMarket)
+	for ndx := range m.Markets {
+		market := &m.Markets[ndx]
+		body, ok := a.FindExact(market.Body)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("market for unknown body %q", market.Body))
+			continue
+		}
+		m.byBody[body.ID] = market
+		for name := range market.Prices {
+			if _, err := m.FindCommodity(name); err != nil {
+				warnings = append(warnings, fmt.Sprintf("market for %s: %s", body.Name, err))
+			}
+		}
+	}
+	for _, tier := range m.Tiers {
+		for _, name := range tier.Commodities {
+			if _, err := m.FindCommodity(name); err != nil {
+				warnings = append(warnings, fmt.Sprintf("tier %d: %s", tier.Tier, err))
+			}
+		}
+	}
+	return warnings
+}
+
+func (m *MarketDB) Tier(tier int64) (MarketTier, bool) {
+	for _, t := range m.Tiers {
+		if t.Tier == tier {
+			return t, true
+		}
+	}
+	return MarketTier{}, false
+}
+
+// TierName is the tier's name from the market file, or its number if the
+// file doesn't name it.
+func (m *MarketDB) TierName(tier int64) string {
+	if tier == MARKET_NONE {
+		return "none"
+	}
+	if t, ok := m.Tier(tier); ok && t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("tier %d", tier)
+}
+
+// ParseTier accepts a tier number or name.
+func (m *MarketDB) ParseTier(s string) (int64, error) {
+	if tier, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return tier, nil
+	}
+	for _, t := range m.Tiers {
+		if strings.EqualFold(t.Name, s) {
+			return t.Tier, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown market type %q", s)
+}
+
+// FindCommodity matches name exactly, ignoring case, or as the prefix of a
+// single commodity.
+func (m *MarketDB) FindCommodity(name string) (Commodity, error) {
+	var matches []Commodity
+	for _, commodity := range m.Commodities {
+		if strings.EqualFold(commodity.Name, name) {
+			return commodity, nil
+		}
+		if strings.HasPrefix(strings.ToLower(commodity.Name), strings.ToLower(name)) {
+			matches = append(matches, commodity)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Commodity{}, fmt.Errorf("unknown commodity %q", name)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for ndx, commodity := range matches {
+		names[ndx] = commodity.Name
+	}
+	return Commodity{}, fmt.Errorf("commodity %q is ambiguous, it matches %s", name, strings.Join(names, ", "))
+}
+
+func factor(f float64) float64 {
+	if f == 0 {
+		return 1
+	}
+	return f
+}
+
+// Prices lists what body's market trades, keyed by commodity name.  Tier
+// prices come from the base prices, and any prices given for the body
+// itself replace them.
+func (m *MarketDB) Prices(body *AstralBody) map[string]Price {
+	prices := make(map[string]Price)
+	if body.Market == MARKET_NONE {
+		return prices
+	}
+	if tier, ok := m.Tier(body.Market); ok {
+		for _, name := range tier.Commodities {
+			commodity, err := m.FindCommodity(name)
+			if err != nil {
+				continue
+			}
+			prices[commodity.Name] = Price{
+				Buy:  commodity.BasePrice * factor(tier.BuyFactor),
+				Sell: commodity.BasePrice * factor(tier.SellFactor),
+			}
+		}
+	}
+	if market, ok := m.byBody[body.ID]; ok {
+		for name, price := range market.Prices {
+			if commodity, err := m.FindCommodity(name); err == nil {
+				name = commodity.Name
+			}
+			prices[name] = price
+		}
+	}
+	return prices
+}
+
+func (m *MarketDB) Price(body *AstralBody, commodity string) (Price, bool) {
+	price, ok := m.Prices(body)[commodity]
+	return price, ok
+}
+
+type MarketResult struct {
+	Body     *AstralBody
+	Route    *Route
+	Distance float64
+	ETA      time.Duration
+	Price    Price
+}
+
+// FindMarkets returns the bodies with a market that pass filter, quickest
+// to reach from source first.  With commodity set only markets trading it
+// are returned.
+func FindMarkets(source *AstralBody, commodity string, speed float64, filter func(AstralBody) bool) ([]MarketResult, error) {
+	var results []MarketResult
+	for _, loc := range NavComp.bodyLocations() {
+		body := loc.Body
+		if body.Market == MARKET_NONE || body == source || (filter != nil && !filter(*body)) {
+			continue
+		}
+		result := MarketResult{Body: body, Distance: source.DistanceToObject(*body)}
+		if commodity != "" {
+			price, ok := Markets.Price(body, commodity)
+			if !ok {
+				continue
+			}
+			result.Price = price
+		}
+		route, err := BestRoute(source, body)
+		if err != nil {
+			return nil, fmt.Errorf("error routing to %s: %w", body.Name, err)
+		}
+		result.Route = route
+		result.ETA, _ = route.GetStatement(speed)
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].ETA < results[j].ETA
+	})
+	return results, nil
+}
+
+func formatPrice(p float64) string {
+	if p == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", p)
+}
+
+func PrintMarkets(source *AstralBody, commodity string, results []MarketResult, numResults int) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	header := table.Row{"#", "Market", "Empire", "Type", "Market Type"}
+	if commodity != "" {
+		header = append(header, "Buy", "Sell")
+	}
+	t.AppendHeader(append(header, "Distance", "Route", "ETA"))
+	for ndx, result := range results {
+		if ndx >= numResults {
+			break
+		}
+		route := "DIRECT"
+		if !result.Route.IsDirect {
+			route = "GATED"
+		}
+		row := table.Row{ndx + 1, result.Body.Name, result.Body.EmpireName(), result.Body.KindName(), Markets.TierName(result.Body.Market)}
+		if commodity != "" {
+			row = append(row, formatPrice(result.Price.Buy), formatPrice(result.Price.Sell))
+		}
+		t.AppendRow(append(row, fmt.Sprintf("%4.2f", result.Distance), route, result.ETA.Truncate(time.Second)))
+	}
+	title := "Markets"
+	if commodity != "" {
+		title = fmt.Sprintf("Markets trading %s", commodity)
+	}
+	fmt.Printf("%s nearest %s:\n%s\n", title, source.Label(), t.Render())
+}
+
+func runMarket(cfg *Config, args []string) error {
+	marketCmd := flag.NewFlagSet("market", flag.ContinueOnError)
+	marketSource := marketCmd.String("source", "", "Body to travel from")
+	marketType := marketCmd.String("type", "", "Only include these market types, comma separated tier numbers or names")
+	marketCommodity := marketCmd.String("commodity", "", "Only include markets trading this commodity")
+	marketSpeed := marketCmd.Float64("speed", 22, "Speed in knots")
+	marketNumResults := marketCmd.Int("num-results", 10, "Number of results to display")
+	marketExact := marketCmd.Bool("exact", false, "Only accept an exact ID, name or alias")
+	marketFilter := AddBodyFilterFlags(marketCmd)
+	cfg.AddFlags(marketCmd)
+	if err := parseFlags(marketCmd, args); err != nil {
+		return err
+	}
+	bodyFilter, err := marketFilter()
+	if err != nil {
+		return err
+	}
+	if *marketSource == "" {
+		return usageErrorf("source is required, none supplied")
+	}
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+	if err := cfg.LoadMarket(*marketCommodity != ""); err != nil {
+		return err
+	}
+	var tiers []int64
+	for _, s := range splitList(*marketType) {
+		tier, err := Markets.ParseTier(s)
+		if err != nil {
+			return usageErrorf("%s", err)
+		}
+		tiers = append(tiers, tier)
+	}
+	commodity := ""
+	if *marketCommodity != "" {
+		c, err := Markets.FindCommodity(*marketCommodity)
+		if err != nil {
+			return usageErrorf("%s", err)
+		}
+		commodity = c.Name
+	}
+	source, err := cfg.ResolveBody(*marketSource, *marketExact)
+	if err != nil {
+		return fmt.Errorf("error looking up source %s: %w", *marketSource, err)
+	}
+	filter := func(body AstralBody) bool {
+		if !bodyFilter.Matches(body) {
+			return false
+		}
+		if len(tiers) == 0 {
+			return true
+		}
+		for _, tier := range tiers {
+			if body.Market == tier {
+				return true
+			}
+		}
+		return false
+	}
+	results, err := FindMarkets(source, commodity, *marketSpeed, filter)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no markets found")
+	}
+	PrintMarkets(source, commodity, results, *marketNumResults)
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testMarkets loads a small navcomp into NavComp, with gates 10000 parsecs
+// apart, along with a market file and an empty route cache.  Everything is
+// at 1000 cochranes so route times only depend on distance:
+//
+//	Gate A  x=1        Gate B  x=10001
+//	Farm    x=11    Outpost, cheap Food
+//	Depot   x=61    no market key at all
+//	Mill    x=111   Standard, pays well for Food
+//	City    x=10021 Standard, near Gate B
+func testMarkets(t *testing.T) *ATSData {
+	t.Helper()
+	navComp, markets, cache, gateNames := NavComp, Markets, routeCache, GateNames
+	t.Cleanup(func() {
+		NavComp, Markets, routeCache, GateNames = navComp, markets, cache, gateNames
+		if NavComp != nil {
+			NavComp.resolveGates()
+		}
+	})
+	GateNames = []string{"Gate A", "Gate B"}
+	atsData, err := ParseATSData([]byte(`{"ATS_Navcomp_DB": {"version": 1, "empires": [{"name": "Independent", "borders": [],
+		"planets": [
+			{"name": "Farm", "x": 11, "y": 0, "z": 0, "cochranes": 1000, "market": 0},
+			{"name": "Depot", "x": 61, "y": 0, "z": 0, "cochranes": 1000},
+			{"name": "City", "x": 10021, "y": 0, "z": 0, "cochranes": 1000, "market": 2}
+		],
+		"stations": [
+			{"name": "Gate A", "x": 1, "y": 0, "z": 0, "cochranes": 1000, "market": -1},
+			{"name": "Gate B", "x": 10001, "y": 0, "z": 0, "cochranes": 1000, "market": -1},
+			{"name": "Mill", "x": 111, "y": 0, "z": 0, "cochranes": 1000, "market": 2}
+		]}]}}`), "atsdata.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	NavComp = atsData
+	Markets = &MarketDB{
+		Version: 1,
+		Tiers: []MarketTier{
+			{Tier: 0, Name: "Outpost", Commodities: []string{"Food"}, BuyFactor: 0.5, SellFactor: 0.4},
+			{Tier: 2, Name: "Standard", Commodities: []string{"Food", "Ore"}, BuyFactor: 1.2},
+		},
+		Commodities: []Commodity{{Name: "Food", BasePrice: 10}, {Name: "Ore", BasePrice: 100}, {Name: "Organics", BasePrice: 20}},
+		Markets:     []BodyMarket{{Body: "mill", Prices: map[string]Price{"food": {Buy: 40, Sell: 30}}}},
+	}
+	if warnings := Markets.Link(atsData); len(warnings) > 0 {
+		t.Fatalf("Link() warnings %q", warnings)
+	}
+	routeCache = &RouteCache{Version: CACHE_VERSION, RouteMap: make(map[string]Route)}
+	return atsData
+}
+
+func TestMissingMarketIsNone(t *testing.T) {
+	atsData := loadTestNavComp(t)
+	tests := []struct {
+		name string
+		want int64
+	}{
+		{name: "Vulcan", want: 2},
+		// No market key, used to read as the lowest tier
+		{name: "IKB wej qogh puQmo'", want: MARKET_NONE},
+		{name: "Transwarp Gate B-03", want: MARKET_NONE},
+	}
+	for _, tt := range tests {
+		if body := findBody(t, atsData, tt.name); body.Market != tt.want {
+			t.Errorf("%s has market %d, want %d", tt.name, body.Market, tt.want)
+		}
+	}
+
+	// Writing the data out keeps bodies without a market that way
+	fname := filepath.Join(t.TempDir(), "atsdata.json")
+	if err := testMarkets(t).WriteToFile(fname); err != nil {
+		t.Fatal(err)
+	}
+	rawBytes, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := ParseATSData(rawBytes, fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Depot", "Farm"} {
+		if before, after := findBody(t, NavComp, name).Market, findBody(t, written, name).Market; before != after {
+			t.Errorf("%s market %d written back as %d", name, before, after)
+		}
+	}
+}
+
+func TestMarketPrices(t *testing.T) {
+	testMarkets(t)
+	tests := []struct {
+		name string
+		want map[string]Price
+	}{
+		{name: "Farm", want: map[string]Price{"Food": {Buy: 5, Sell: 4}}},
+		{name: "Depot", want: map[string]Price{}},
+		{name: "City", want: map[string]Price{"Food": {Buy: 12, Sell: 10}, "Ore": {Buy: 120, Sell: 100}}},
+		// The body's own price replaces the tier's, under the commodity's
+		// proper name
+		{name: "Mill", want: map[string]Price{"Food": {Buy: 40, Sell: 30}, "Ore": {Buy: 120, Sell: 100}}},
+	}
+	for _, tt := range tests {
+		got := Markets.Prices(findBody(t, NavComp, tt.name))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s prices %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for commodity, price := range tt.want {
+			if got[commodity] != price {
+				t.Errorf("%s %s price %v, want %v", tt.name, commodity, got[commodity], price)
+			}
+		}
+	}
+
+	if got := Markets.TierName(MARKET_NONE); got != "none" {
+		t.Errorf("TierName(MARKET_NONE) = %q", got)
+	}
+	if got := Markets.TierName(1); got != "tier 1" {
+		t.Errorf("TierName(1) = %q", got)
+	}
+	if tier, err := Markets.ParseTier("standard"); err != nil || tier != 2 {
+		t.Errorf("ParseTier(standard) = %d, %v", tier, err)
+	}
+	if commodity, err := Markets.FindCommodity("fo"); err != nil || commodity.Name != "Food" {
+		t.Errorf("FindCommodity(fo) = %v, %v", commodity, err)
+	}
+	if _, err := Markets.FindCommodity("or"); err == nil {
+		t.Errorf("FindCommodity(or) matched one of Ore and Organics")
+	}
+}
+
+func TestFindMarkets(t *testing.T) {
+	testMarkets(t)
+	farm := findBody(t, NavComp, "Farm")
+	tests := []struct {
+		name      string
+		commodity string
+		filter    func(AstralBody) bool
+		want      []string
+	}{
+		// City is only 30 parsecs away through the gates
+		{name: "any", want: []string{"City", "Mill"}},
+		{name: "commodity", commodity: "Ore", want: []string{"City", "Mill"}},
+		{name: "commodity nobody trades", commodity: "Organics"},
+		{name: "filtered", filter: func(body AstralBody) bool { return body.Kind == KIND_STATIONS }, want: []string{"Mill"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := FindMarkets(farm, tt.commodity, 9, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Body.Name)
+			}
+			if !sameNames(got, tt.want) {
+				t.Errorf("FindMarkets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Depot has no market, so nothing is bought there
+	runs, err := PlanTrades(TradeOptions{Cargo: 10, Speed: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, run := range runs {
+		if run.From.Name == "Depot" || run.To.Name == "Depot" {
+			t.Errorf("trade run from %s to %s", run.From.Name, run.To.Name)
+		}
+	}
+}
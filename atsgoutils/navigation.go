@@ -17,7 +17,7 @@ type Route struct {
 func (r *Route) GetStatement(speed float64) (time.Duration, string) {
 	if r.IsDirect {
 		executeTime := r.TimeToExecute(speed).Truncate(time.Second)
-		return executeTime, fmt.Sprintf("DIRECT: %s to %s ETA: %s", r.Source.Name, r.Target.Name, executeTime)
+		return executeTime, fmt.Sprintf("DIRECT: %s %s to %s %s ETA: %s", r.Source.Label(), FormatPoint(r.Source.Point), r.Target.Label(), FormatPoint(r.Target.Point), executeTime)
 	}
 	statement := "GATED:\n"
 	totalDuration := time.Duration(0)
@@ -49,17 +49,19 @@ func (r *Route) TimeToExecute(speed float64) time.Duration {
 		rTime := (r.Distance / velocity) * 1e9
 		return time.Duration(rTime)
 	}
-	timeToExecute := float64(0)
+	// A gated route's Source and Target are on different legs, so its
+	// time is that of its legs rather than of its total distance
+	timeToExecute := time.Duration(0)
 	for _, stop := range r.Stops {
-		stopTime := stop.TimeToExecute(speed)
-		timeToExecute += stopTime.Seconds()
+		timeToExecute += stop.TimeToExecute(speed)
 	}
-	rTime := (r.Distance / velocity) * 1e9
-	return time.Duration(rTime)
+	return timeToExecute
 }
 
+// GetRouteName is the route cache key, built from body IDs so that renaming
+// a body or two bodies sharing a name don't mix up routes.
 func GetRouteName(source, target *AstralBody) string {
-	return fmt.Sprintf("%s to %s", source.Name, target.Name)
+	return fmt.Sprintf("%s to %s", source.ID, target.ID)
 }
 
 func DirectRoute(sourceObj, targetObj *AstralBody) (Route, error) {
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// warpTime is how long distance parsecs takes at speed through space of the
+// given cochranes.
+func warpTime(distance, cochranes, speed float64) time.Duration {
+	parsecsPerSecond := math.Pow(speed, 3.33) * cochranes * LIGHTSPEED / PARSEC
+	return time.Duration(distance / parsecsPerSecond * float64(time.Second))
+}
+
+func TestTimeToExecute(t *testing.T) {
+	source := &AstralBody{Name: "Source", Cochranes: 1000}
+	gate := &AstralBody{Name: "Gate", Cochranes: 500}
+	far := &AstralBody{Name: "Far Gate", Cochranes: 500}
+	target := &AstralBody{Name: "Target", Cochranes: 3000}
+	unknown := &AstralBody{Name: "Uncharted"}
+	gated := &Route{
+		Source:   source,
+		Target:   target,
+		Distance: 300,
+		Stops: []*Route{
+			{Source: source, Target: gate, IsDirect: true, Distance: 100},
+			{Source: far, Target: target, IsDirect: true, Distance: 200},
+		},
+	}
+	tests := []struct {
+		name  string
+		route *Route
+		speed float64
+		want  time.Duration
+	}{
+		{name: "direct", route: &Route{Source: source, Target: target, IsDirect: true, Distance: 300}, speed: 9, want: warpTime(300, 2000, 9)},
+		{name: "faster", route: &Route{Source: source, Target: target, IsDirect: true, Distance: 300}, speed: 18, want: warpTime(300, 2000, 18)},
+		{name: "missing cochranes", route: &Route{Source: source, Target: unknown, IsDirect: true, Distance: 50}, speed: 9, want: warpTime(50, (1000+AVG_COCHRANE_DENSITY)/2, 9)},
+		{name: "same place", route: &Route{Source: source, Target: source, IsDirect: true}, speed: 9},
+		// Each leg at its own cochranes, not the whole distance at the
+		// average of the two ends
+		{name: "gated", route: gated, speed: 9, want: warpTime(100, 750, 9) + warpTime(200, 1750, 9)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.route.TimeToExecute(tt.speed)
+			if diff := got - tt.want; diff < -time.Microsecond || diff > time.Microsecond {
+				t.Errorf("TimeToExecute(%v) = %s, want %s", tt.speed, got, tt.want)
+			}
+		})
+	}
+}
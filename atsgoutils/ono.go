@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jedib0t/go-pretty/table"
 )
@@ -31,8 +32,16 @@ func (obd *OrderedByDist) AddHeadingResult(hr HeadingResult) {
 			break
 		} else if hr.Distance == ehr.Distance {
 			// I want to capture all items at the same row for the same distance
-			newName := fmt.Sprintf("%s, %s", ehr.BodyOfInterest.Name, hr.BodyOfInterest.Name)
-			obd.HeadingResults[ndx].BodyOfInterest.Name = newName
+			merged := &obd.HeadingResults[ndx].BodyOfInterest
+			merged.Name = fmt.Sprintf("%s, %s", ehr.BodyOfInterest.Name, hr.BodyOfInterest.Name)
+			if merged.Kind != hr.BodyOfInterest.Kind {
+				merged.Kind = joinDistinct(merged.Kind, hr.BodyOfInterest.Kind)
+			}
+			if merged.EmpireName() != hr.BodyOfInterest.EmpireName() {
+				// The row is only for display, so a stand in empire is
+				// enough to show both names
+				merged.Empire = &Empire{Name: joinDistinct(merged.EmpireName(), hr.BodyOfInterest.EmpireName())}
+			}
 			targetNdx = -2
 			break
 		}
@@ -46,41 +55,55 @@ func (obd *OrderedByDist) AddHeadingResult(hr HeadingResult) {
 	}
 }
 
-func FilterBodiesByDistanceFromTarget(target *AstralBody, spaceRange *float64) func(AstralBody) bool {
-	return func(astralBody AstralBody) bool {
-		dist := target.DistanceToObject(astralBody)
-		if dist <= *spaceRange {
-			hr := HeadingResult{
-				Distance:         dist,
-				BodyOfInterest:   astralBody,
-				Time:             -1,
-				ContainingRadius: *spaceRange,
-			}
-			OrderedList.AddHeadingResult(hr)
-			return true
-		}
-		return false
+// joinDistinct adds s to a comma separated list unless it is already there.
+func joinDistinct(list, s string) string {
+	if containsFold(strings.Split(list, ", "), s) {
+		return list
+	}
+	return list + ", " + s
+}
+
+// kindNames turns a merged "planets, stations" into "planet, station".
+func kindNames(kinds string) string {
+	names := strings.Split(kinds, ", ")
+	for ndx := range names {
+		names[ndx] = strings.TrimSuffix(names[ndx], "s")
+	}
+	return strings.Join(names, ", ")
+}
+
+func addNearbyObject(target *AstralBody, astralBody AstralBody, spaceRange *float64) {
+	hr := HeadingResult{
+		Distance:         target.DistanceToObject(astralBody),
+		BodyOfInterest:   astralBody,
+		Time:             -1,
+		ContainingRadius: *spaceRange,
 	}
+	OrderedList.AddHeadingResult(hr)
 }
 
 func PrintBodies(target *AstralBody, numResults *int) {
 	t := table.NewWriter()
 	t.SetStyle(table.StyleLight)
 	ndx := 0
-	t.AppendHeader(table.Row{"#", "Object", "Distance"})
+	t.AppendHeader(table.Row{"#", "Object", "Empire", "Type", "Location", "Distance"})
 	for _, hr := range OrderedList.HeadingResults {
 		ndx++
-		t.AppendRow(table.Row{ndx, fmt.Sprintf("%-50s", hr.BodyOfInterest.Name), fmt.Sprintf("%4.2f", hr.Distance)})
+		body := hr.BodyOfInterest
+		t.AppendRow(table.Row{ndx, fmt.Sprintf("%-50s", body.Name), body.EmpireName(), kindNames(body.Kind), FormatPoint(body.Point), fmt.Sprintf("%4.2f", hr.Distance)})
 		if ndx >= *numResults {
 			break
 		}
 	}
-	fmt.Printf("Objects near %s:\n%s", target.Name, t.Render())
+	fmt.Printf("Objects near %s at %s:\n%s", target.Label(), FormatPoint(target.Point), t.Render())
 }
 
-func getNearbyObjects(target *AstralBody, spaceRange *float64, numResults *int) {
-	filterFunc := FilterBodiesByDistanceFromTarget(target, spaceRange)
+func getNearbyObjects(target *AstralBody, spaceRange *float64, numResults *int, filter func(AstralBody) bool) {
 	OrderedList.Reset()
-	NavComp.FilterBodies(filterFunc)
+	for _, body := range NavComp.Index.WithinRadius(target.Point, *spaceRange) {
+		if filter == nil || filter(*body) {
+			addNearbyObject(target, *body, spaceRange)
+		}
+	}
 	PrintBodies(target, numResults)
 }
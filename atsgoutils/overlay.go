@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This file layers overlay files on top of the navcomp data, for private
+// outposts and corrected coordinates that aren't in the public data.  An
+// overlay has the same layout as atsdata.json, but each empire, body and
+// border in it is matched against the data loaded so far:
+//
+//   - an entry that matches is updated with only the fields the overlay
+//     gives, so {"name": "Vulcan", "x": -9190.5} just moves Vulcan
+//   - an entry that doesn't match is added
+//   - an entry with "hide": true removes the match
+//
+// Empires and borders match by name, and bodies by "id" when given or
+// otherwise by name within the same empire's planets or stations.  Layers
+// are applied in order, so later overlays win.
+
+const OVERLAY_ENV_VAR = "ATSGOUTILS_OVERLAYS"
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+type overlayNavcompDB struct {
+	Empires []json.RawMessage   `json:"empires"`
+	Aliases map[string][]string `json:"aliases"`
+}
+
+type overlayData struct {
+	NavcompDB overlayNavcompDB `json:"ATS_Navcomp_DB"`
+}
+
+// overlayEntry is the fields used to match an overlay entry, the rest are
+// applied to the match.
+type overlayEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Hide bool   `json:"hide"`
+}
+
+// overlayFields returns raw without the keys that aren't fields of the
+// matched entry, ready to be unmarshalled on top of it.
+func overlayFields(raw json.RawMessage, drop ...string) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "hide")
+	for _, key := range drop {
+		delete(fields, key)
+	}
+	return json.Marshal(fields)
+}
+
+// ApplyOverlay merges the overlay file into the data, recording layer on
+// every body and border it adds or changes.  Warnings are returned for
+// hidden entries that don't match anything.  IndexBodies must be called
+// afterwards.
+func (a *ATSData) ApplyOverlay(filename, layer string) ([]string, error) {
+	var overlay overlayData
+	rawBytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", filename, err)
+	}
+	err = json.Unmarshal(rawBytes, &overlay)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling json: %w", err)
+	}
+	var warnings []string
+	for _, rawEmpire := range overlay.NavcompDB.Empires {
+		var entry overlayEntry
+		if err := json.Unmarshal(rawEmpire, &entry); err != nil {
+			return nil, fmt.Errorf("error unmarshalling empire: %w", err)
+		}
+		empireNdx := -1
+		for ndx := range a.NavcompDB.Empires {
+			if strings.EqualFold(a.NavcompDB.Empires[ndx].Name, entry.Name) {
+				empireNdx = ndx
+				break
+			}
+		}
+		if entry.Hide {
+			if empireNdx < 0 {
+				warnings = append(warnings, fmt.Sprintf("cannot hide empire %s, it doesn't exist", entry.Name))
+				continue
+			}
+			a.NavcompDB.Empires = append(a.NavcompDB.Empires[:empireNdx], a.NavcompDB.Empires[empireNdx+1:]...)
+			continue
+		}
+		if empireNdx < 0 {
+			a.NavcompDB.Empires = append(a.NavcompDB.Empires, Empire{Name: entry.Name})
+			empireNdx = len(a.NavcompDB.Empires) - 1
+		}
+		empire := &a.NavcompDB.Empires[empireNdx]
+		var lists struct {
+			Borders  []json.RawMessage `json:"borders"`
+			Planets  []json.RawMessage `json:"planets"`
+			Stations []json.RawMessage `json:"stations"`
+		}
+		if err := json.Unmarshal(rawEmpire, &lists); err != nil {
+			return nil, fmt.Errorf("error unmarshalling empire %s: %w", entry.Name, err)
+		}
+		fields, err := overlayFields(rawEmpire, "borders", "planets", "stations")
+		if err == nil {
+			err = json.Unmarshal(fields, empire)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error applying empire %s: %w", entry.Name, err)
+		}
+		for _, rawBorder := range lists.Borders {
+			warning, err := overlayBorder(empire, rawBorder, layer)
+			if err != nil {
+				return nil, fmt.Errorf("error applying border in %s: %w", empire.Name, err)
+			}
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+		for _, list := range []struct {
+			kind   string
+			bodies *[]AstralBody
+			raw    []json.RawMessage
+		}{{KIND_PLANETS, &empire.Planets, lists.Planets}, {KIND_STATIONS, &empire.Stations, lists.Stations}} {
+			for _, rawBody := range list.raw {
+				warning, err := overlayBody(a, empire.Name, list.kind, list.bodies, rawBody, layer)
+				if err != nil {
+					return nil, fmt.Errorf("error applying %s in %s: %w", list.kind, empire.Name, err)
+				}
+				if warning != "" {
+					warnings = append(warnings, warning)
+				}
+			}
+		}
+	}
+	for id, aliases := range overlay.NavcompDB.Aliases {
+		if a.NavcompDB.Aliases == nil {
+			a.NavcompDB.Aliases = make(map[string][]string)
+		}
+		a.NavcompDB.Aliases[id] = appendMissing(a.NavcompDB.Aliases[id], aliases...)
+	}
+	return warnings, nil
+}
+
+func overlayBorder(empire *Empire, raw json.RawMessage, layer string) (string, error) {
+	var entry overlayEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return "", err
+	}
+	ndx := -1
+	for indx := range empire.Borders {
+		if strings.EqualFold(empire.Borders[indx].Name, entry.Name) {
+			ndx = indx
+			break
+		}
+	}
+	if entry.Hide {
+		if ndx < 0 {
+			return fmt.Sprintf("cannot hide border %s in %s, it doesn't exist", entry.Name, empire.Name), nil
+		}
+		empire.Borders = append(empire.Borders[:ndx], empire.Borders[ndx+1:]...)
+		return "", nil
+	}
+	if ndx < 0 {
+		empire.Borders = append(empire.Borders, Border{})
+		ndx = len(empire.Borders) - 1
+	}
+	fields, err := overlayFields(raw)
+	if err != nil {
+		return "", err
+	}
+	border := &empire.Borders[ndx]
+	if err := json.Unmarshal(fields, border); err != nil {
+		return "", err
+	}
+	border.Layer = layer
+	border.CreatePoint()
+	return "", nil
+}
+
+func overlayBody(a *ATSData, empireName, kind string, bodies *[]AstralBody, raw json.RawMessage, layer string) (string, error) {
+	var entry overlayEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return "", err
+	}
+	ndx := -1
+	for indx, body := range *bodies {
+		if entry.ID != "" && body.ID == entry.ID {
+			ndx = indx
+			break
+		}
+		if entry.ID == "" && strings.EqualFold(body.Name, entry.Name) {
+			ndx = indx
+			break
+		}
+	}
+	name := entry.Name
+	if name == "" {
+		name = entry.ID
+	}
+	if entry.Hide {
+		if ndx < 0 {
+			return fmt.Sprintf("cannot hide %s %s in %s, it doesn't exist", strings.TrimSuffix(kind, "s"), name, empireName), nil
+		}
+		*bodies = append((*bodies)[:ndx], (*bodies)[ndx+1:]...)
+		return "", nil
+	}
+	if ndx < 0 {
+		*bodies = append(*bodies, AstralBody{Market: MARKET_NONE})
+		ndx = len(*bodies) - 1
+	}
+	fields, err := overlayFields(raw)
+	if err != nil {
+		return "", err
+	}
+	body := &(*bodies)[ndx]
+	market := body.Market
+	if err := json.Unmarshal(fields, body); err != nil {
+		return "", err
+	}
+	// Unlike in the data, leaving the market out of an overlay keeps it
+	var marketSet struct {
+		Market *int64 `json:"market"`
+	}
+	if err := json.Unmarshal(fields, &marketSet); err == nil && marketSet.Market == nil {
+		body.Market = market
+	}
+	body.Layer = layer
+	body.CreatePoint()
+	return "", nil
+}
+
+// layerName is how a layer is shown, the file's name without directories.
+func layerName(filename string) string {
+	return filepath.Base(filename)
+}
+
+// ResolveOverlayPaths returns the overlays given with --overlay, or in
+// $ATSGOUTILS_OVERLAYS separated like $PATH.
+func (c *Config) ResolveOverlayPaths() []string {
+	if len(c.Overlays) > 0 {
+		return c.Overlays
+	}
+	var overlays []string
+	for _, overlay := range filepath.SplitList(os.Getenv(OVERLAY_ENV_VAR)) {
+		if overlay != "" {
+			overlays = append(overlays, overlay)
+		}
+	}
+	return overlays
+}
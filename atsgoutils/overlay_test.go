@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+// findBody looks up a body by exact name, ID or alias.
+func findBody(t *testing.T, a *ATSData, name string) *AstralBody {
+	t.Helper()
+	body, ok := a.FindExact(name)
+	if !ok {
+		t.Fatalf("%s not found", name)
+	}
+	return body
+}
+
+func TestApplyOverlay(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlay string
+		check   func(t *testing.T, a *ATSData)
+		// warnings is how many warnings ApplyOverlay should return
+		warnings int
+	}{
+		{
+			name:    "add",
+			overlay: `{"empires": [{"name": "Federation", "stations": [{"name": "Secret Outpost", "x": 1, "y": 2, "z": 3}]}, {"name": "Pirates", "planets": [{"name": "Hideout", "x": 4, "y": 5, "z": 6}]}]}`,
+			check: func(t *testing.T, a *ATSData) {
+				outpost := findBody(t, a, "Secret Outpost")
+				if outpost.Point != (Point{X: 1, Y: 2, Z: 3}) || outpost.EmpireName() != "Federation" || outpost.Kind != KIND_STATIONS || outpost.Layer != "test" || outpost.Market != MARKET_NONE {
+					t.Errorf("Secret Outpost is %+v", *outpost)
+				}
+				if hideout := findBody(t, a, "Hideout"); hideout.EmpireName() != "Pirates" {
+					t.Errorf("Hideout is in %q, want Pirates", hideout.EmpireName())
+				}
+			},
+		},
+		{
+			name:    "override",
+			overlay: `{"empires": [{"name": "federation", "planets": [{"name": "Vulcan", "x": -9190.5}]}]}`,
+			check: func(t *testing.T, a *ATSData) {
+				vulcan := findBody(t, a, "Vulcan")
+				if vulcan.Name != "Vulcan" || vulcan.Point != (Point{X: -9190.5, Y: 60, Z: -0.6}) || vulcan.Cochranes != 1545 || vulcan.Market != 2 || vulcan.Layer != "test" {
+					t.Errorf("Vulcan is %+v, want only X changed", *vulcan)
+				}
+			},
+		},
+		{
+			name:    "override by ID",
+			overlay: `{"empires": [{"name": "Federation", "planets": [{"id": "federation/vulcan", "name": "Vulcan Prime"}]}]}`,
+			check: func(t *testing.T, a *ATSData) {
+				if body := findBody(t, a, "Vulcan Prime"); body.ID != "federation/vulcan" {
+					t.Errorf("Vulcan Prime has ID %q", body.ID)
+				}
+				if _, ok := a.FindExact("Vulcan"); ok {
+					t.Errorf("Vulcan is still found by its old name")
+				}
+			},
+		},
+		{
+			name:    "hide",
+			overlay: `{"empires": [{"name": "Federation", "planets": [{"name": "Vulcan", "hide": true}, {"name": "Nowhere", "hide": true}]}, {"name": "Nobody", "hide": true}]}`,
+			check: func(t *testing.T, a *ATSData) {
+				if _, ok := a.FindExact("Vulcan"); ok {
+					t.Errorf("Vulcan wasn't hidden")
+				}
+			},
+			warnings: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atsData, err := ParseATSDataFromFile("atsdata.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			overlay := writeTestFile(t, "overlay.json", `{"ATS_Navcomp_DB": `+tt.overlay+`}`)
+			warnings, err := atsData.ApplyOverlay(overlay, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("got warnings %q, want %d", warnings, tt.warnings)
+			}
+			atsData.IndexBodies()
+			tt.check(t, atsData)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// This file lets the pilot choose between bodies when a name is ambiguous.
+// Commands that take body names should resolve them with Config.ResolveBody
+// so they all prompt the same way, or fail with the candidate list when
+// there is no terminal to prompt on.
+
+// Interactive reports whether the user can be prompted, which needs stdin
+// to be a terminal and --non-interactive not to be set.
+func (c *Config) Interactive() bool {
+	if c.NonInteractive {
+		return false
+	}
+	// A character device isn't enough, /dev/null is one too
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ResolveBody resolves query against NavComp, asking the user to choose
+// when it is ambiguous and they can be prompted.  A bookmark is used when
+// query names one exactly and no body has that exact ID, name or alias.
+func (c *Config) ResolveBody(query string, exact bool) (*AstralBody, error) {
+	if _, ok := NavComp.FindExact(query); !ok {
+		if bookmark, ok := NavComp.FindBookmark(query); ok {
+			return bookmark, nil
+		}
+	}
+	body, err := NavComp.ResolveObject(query, exact)
+	var ambiguous *AmbiguousNameError
+	if err == nil || !errors.As(err, &ambiguous) || !c.Interactive() {
+		return body, err
+	}
+	return PickCandidate(os.Stdin, os.Stderr, ambiguous)
+}
+
+// PickCandidate prints the numbered candidates to out and reads the user's
+// choice from in until they pick one, give up with "q" or input ends.
+func PickCandidate(in io.Reader, out io.Writer, ambiguous *AmbiguousNameError) (*AstralBody, error) {
+	fmt.Fprintf(out, "%q matches %d objects:\n%s", ambiguous.Query, len(ambiguous.Candidates), FormatCandidates(ambiguous.Candidates))
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(out, "Choose 1-%d (q to cancel): ", len(ambiguous.Candidates))
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return nil, ambiguous
+		}
+		answer := strings.TrimSpace(scanner.Text())
+		if strings.EqualFold(answer, "q") {
+			return nil, ambiguous
+		}
+		choice, err := strconv.Atoi(answer)
+		if err != nil || choice < 1 || choice > len(ambiguous.Candidates) {
+			fmt.Fprintf(out, "%q is not a choice\n", answer)
+			continue
+		}
+		return ambiguous.Candidates[choice-1].Body, nil
+	}
+}
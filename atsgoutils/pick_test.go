@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPickCandidate(t *testing.T) {
+	first := &AstralBody{Name: "Bajor", ID: "bajoran/bajor"}
+	second := &AstralBody{Name: "Bajor", ID: "bajoran/bajor-2"}
+	ambiguous := &AmbiguousNameError{Query: "bajor", Candidates: []Candidate{
+		{bodyLocation: bodyLocation{Empire: "Bajoran", Kind: KIND_PLANETS, Index: 0, Body: first}},
+		{bodyLocation: bodyLocation{Empire: "Bajoran", Kind: KIND_PLANETS, Index: 1, Body: second}},
+	}}
+	tests := []struct {
+		name  string
+		input string
+		want  *AstralBody
+		// prompts is how many times the user should be asked
+		prompts int
+	}{
+		{name: "first", input: "1\n", want: first, prompts: 1},
+		{name: "second with spaces", input: "  2  \n", want: second, prompts: 1},
+		{name: "out of range then valid", input: "0\n3\nbajor\n2\n", want: second, prompts: 4},
+		{name: "quit", input: "Q\n1\n", prompts: 1},
+		{name: "end of input", input: "", prompts: 1},
+		{name: "end of input after a bad choice", input: "x", prompts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got, err := PickCandidate(strings.NewReader(tt.input), &out, ambiguous)
+			if got != tt.want {
+				t.Errorf("PickCandidate() = %v, want %v", got, tt.want)
+			}
+			if tt.want == nil && !errors.Is(err, ambiguous) {
+				t.Errorf("PickCandidate() error = %v, want the ambiguous name error", err)
+			}
+			if tt.want != nil && err != nil {
+				t.Errorf("PickCandidate() error = %v", err)
+			}
+			if !strings.HasPrefix(out.String(), `"bajor" matches 2 objects:`) {
+				t.Errorf("candidates not listed first:\n%s", out.String())
+			}
+			if prompts := strings.Count(out.String(), "Choose 1-2 (q to cancel): "); prompts != tt.prompts {
+				t.Errorf("prompted %d times, want %d:\n%s", prompts, tt.prompts, out.String())
+			}
+		})
+	}
+}
+
+func TestResolveBodyWithoutTerminal(t *testing.T) {
+	navComp := NavComp
+	t.Cleanup(func() { NavComp = navComp })
+	NavComp = parseTestData(t,
+		`{"name": "Bajor", "x": 1, "y": 1, "z": 1}, {"name": "Bajor", "x": 2, "y": 2, "z": 2}`,
+		`{"name": "Deep Space 9", "x": 3, "y": 3, "z": 3}`,
+		"")
+	// Tests never run with a terminal on stdin, so neither config may
+	// prompt and both must fail with the candidates
+	for _, cfg := range []*Config{{}, {NonInteractive: true}} {
+		if cfg.Interactive() {
+			t.Fatalf("Interactive() with NonInteractive %v and no terminal", cfg.NonInteractive)
+		}
+		var ambiguous *AmbiguousNameError
+		if _, err := cfg.ResolveBody("bajor", false); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+			t.Errorf("ResolveBody(bajor) error = %v, want both candidates", err)
+		}
+		if body, err := cfg.ResolveBody("deep space", false); err != nil || body.Name != "Deep Space 9" {
+			t.Errorf("ResolveBody(deep space) = %v, %v", body, err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// This file resolves what a pilot typed into a body.  Every ID, name and
+// alias is ranked against the query and the best match wins, but if the
+// best matches tie between different bodies the query is ambiguous and an
+// error listing the candidates is returned rather than picking one.
+
+type MatchRank int
+
+const (
+	MatchNone MatchRank = iota
+	MatchFuzzy
+	MatchSubstring
+	MatchWordPrefix
+	MatchWord
+	MatchPrefix
+	MatchExact
+)
+
+func (m MatchRank) String() string {
+	switch m {
+	case MatchExact:
+		return "exact"
+	case MatchPrefix:
+		return "prefix"
+	case MatchWord:
+		return "word"
+	case MatchWordPrefix:
+		return "word prefix"
+	case MatchSubstring:
+		return "substring"
+	case MatchFuzzy:
+		return "fuzzy"
+	}
+	return "none"
+}
+
+type Candidate struct {
+	bodyLocation
+	Rank MatchRank
+	// Matched is the ID, name or alias that matched
+	Matched string
+	// EditDistance is only set for fuzzy matches
+	EditDistance int
+}
+
+func (c Candidate) String() string {
+	if NavComp != nil && len(NavComp.Layers) > 1 {
+		return fmt.Sprintf("%-40s %-12s %-8s %s %s match on %q from %s", c.Body.Name, c.Empire, strings.TrimSuffix(c.Kind, "s"), FormatPoint(c.Body.Point), c.Rank, c.Matched, c.Body.Layer)
+	}
+	return fmt.Sprintf("%-40s %-12s %-8s %s %s match on %q", c.Body.Name, c.Empire, strings.TrimSuffix(c.Kind, "s"), FormatPoint(c.Body.Point), c.Rank, c.Matched)
+}
+
+// FormatCandidates numbers candidates from 1 for the user to choose from.
+func FormatCandidates(candidates []Candidate) string {
+	var sb strings.Builder
+	for ndx, candidate := range candidates {
+		fmt.Fprintf(&sb, "\t%2d) %s\n", ndx+1, candidate)
+	}
+	return sb.String()
+}
+
+// better orders candidates best first.
+func (c Candidate) better(o Candidate) bool {
+	if c.Rank != o.Rank {
+		return c.Rank > o.Rank
+	}
+	return c.EditDistance < o.EditDistance
+}
+
+func (c Candidate) ties(o Candidate) bool {
+	return c.Rank == o.Rank && c.EditDistance == o.EditDistance
+}
+
+type NotFoundError struct {
+	Query string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("object %s not found", e.Query)
+}
+
+type AmbiguousNameError struct {
+	Query      string
+	Candidates []Candidate
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("%q is ambiguous, it matches %d objects equally well:\n%s", e.Query, len(e.Candidates), strings.TrimSuffix(FormatCandidates(e.Candidates), "\n"))
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// levenshtein is the number of single character edits between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// maxEditDistance is how many typos a query may have, one per three
+// characters.
+func maxEditDistance(query string) int {
+	return max(1, len([]rune(query))/3)
+}
+
+// rankName ranks a single ID, name or alias against an already lower cased
+// query.  A word prefix match is a run of whole words whose last word only
+// starts with the query's last word, so "rom" finds "Magna Roma" ahead of
+// "Andromeda".  Fuzzy matches compare against the whole name and against each run
+// of words as long as the query, so "cardasia" still finds "Cardassia IV".
+func rankName(query, name string) (MatchRank, int) {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == query:
+		return MatchExact, 0
+	case strings.HasPrefix(lower, query):
+		return MatchPrefix, 0
+	}
+	words := splitWords(lower)
+	queryWords := splitWords(query)
+	if len(queryWords) > 0 && len(queryWords) <= len(words) {
+		for start := 0; start+len(queryWords) <= len(words); start++ {
+			if strings.Join(words[start:start+len(queryWords)], " ") == strings.Join(queryWords, " ") {
+				return MatchWord, 0
+			}
+		}
+		for start := 0; start+len(queryWords) <= len(words); start++ {
+			if wordsHavePrefix(words[start:start+len(queryWords)], queryWords) {
+				return MatchWordPrefix, 0
+			}
+		}
+	}
+	if strings.Contains(lower, query) {
+		return MatchSubstring, 0
+	}
+	best := levenshtein(query, lower)
+	if len(queryWords) > 0 && len(queryWords) <= len(words) {
+		for start := 0; start+len(queryWords) <= len(words); start++ {
+			best = min(best, levenshtein(strings.Join(queryWords, " "), strings.Join(words[start:start+len(queryWords)], " ")))
+		}
+	}
+	if best <= maxEditDistance(query) {
+		return MatchFuzzy, best
+	}
+	return MatchNone, 0
+}
+
+// wordsHavePrefix reports whether words match prefix word for word, except
+// that the last word need only start with prefix's last word.
+func wordsHavePrefix(words, prefix []string) bool {
+	last := len(prefix) - 1
+	for ndx := 0; ndx < last; ndx++ {
+		if words[ndx] != prefix[ndx] {
+			return false
+		}
+	}
+	return strings.HasPrefix(words[last], prefix[last])
+}
+
+// RankObjects returns every body matching query, best first.  With exact
+// set only exact ID, name or alias matches are returned.
+func (a *ATSData) RankObjects(query string, exact bool) []Candidate {
+	query = lookupKey(query)
+	if query == "" {
+		return nil
+	}
+	var candidates []Candidate
+	for _, loc := range a.bodyLocations() {
+		best := Candidate{bodyLocation: loc}
+		if strings.ToLower(loc.Body.ID) == query {
+			// IDs share empire prefixes, so only whole IDs count
+			best = Candidate{bodyLocation: loc, Rank: MatchExact, Matched: loc.Body.ID}
+		}
+		names := append([]string{loc.Body.Name}, loc.Body.AllAliases()...)
+		for _, name := range names {
+			rank, distance := rankName(query, name)
+			candidate := Candidate{bodyLocation: loc, Rank: rank, Matched: name, EditDistance: distance}
+			if candidate.better(best) {
+				best = candidate
+			}
+		}
+		if best.Rank == MatchNone || (exact && best.Rank != MatchExact) {
+			continue
+		}
+		candidates = append(candidates, best)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].better(candidates[j])
+	})
+	return candidates
+}
+
+// ResolveObject returns the single best match for query, a *NotFoundError
+// if nothing matches or an *AmbiguousNameError if the best matches tie.
+func (a *ATSData) ResolveObject(query string, exact bool) (*AstralBody, error) {
+	candidates := a.RankObjects(query, exact)
+	if len(candidates) == 0 {
+		return nil, &NotFoundError{Query: query}
+	}
+	tied := 1
+	for tied < len(candidates) && candidates[tied].ties(candidates[0]) {
+		tied++
+	}
+	if tied > 1 {
+		return nil, &AmbiguousNameError{Query: query, Candidates: candidates[:tied]}
+	}
+	return candidates[0].Body, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRankName(t *testing.T) {
+	tests := []struct {
+		query, name string
+		rank        MatchRank
+		distance    int
+	}{
+		{query: "vulcan", name: "Vulcan", rank: MatchExact},
+		{query: "cardassia", name: "Cardassia IV", rank: MatchPrefix},
+		{query: "roma", name: "Magna Roma", rank: MatchWord},
+		{query: "deep space", name: "Bajoran Deep Space Nine", rank: MatchWord},
+		{query: "rom", name: "Magna Roma", rank: MatchWordPrefix},
+		{query: "deep sp", name: "Bajoran Deep Space Nine", rank: MatchWordPrefix},
+		{query: "rom", name: "Andromeda", rank: MatchSubstring},
+		{query: "cardasia", name: "Cardassia IV", rank: MatchFuzzy, distance: 1},
+		{query: "vulcna", name: "Vulcan", rank: MatchFuzzy, distance: 2},
+		{query: "qonos", name: "Vulcan", rank: MatchNone},
+		{query: "ab", name: "xy", rank: MatchNone},
+	}
+	for _, tt := range tests {
+		rank, distance := rankName(tt.query, tt.name)
+		if rank != tt.rank || distance != tt.distance {
+			t.Errorf("rankName(%q, %q) = %s %d, want %s %d", tt.query, tt.name, rank, distance, tt.rank, tt.distance)
+		}
+	}
+}
+
+func TestResolveObject(t *testing.T) {
+	atsData := loadTestNavComp(t)
+	tests := []struct {
+		query string
+		exact bool
+		// want is the body's name, or empty for an error
+		want      string
+		ambiguous []string
+	}{
+		{query: "Vulcan", want: "Vulcan"},
+		{query: "  VULCAN ", want: "Vulcan"},
+		{query: "federation/vulcan", want: "Vulcan"},
+		{query: "vulca", want: "Vulcan"},
+		{query: "vulca", exact: true},
+		{query: "vulcna", want: "Vulcan"},
+		{query: "rom", ambiguous: []string{"Magna Roma", "Mol'Rihan <New Romulus>"}},
+		{query: "no such place"},
+		{query: ""},
+	}
+	for _, tt := range tests {
+		body, err := atsData.ResolveObject(tt.query, tt.exact)
+		var ambiguous *AmbiguousNameError
+		switch {
+		case tt.want != "":
+			if err != nil || body.Name != tt.want {
+				t.Errorf("ResolveObject(%q, %t) = %v, %v, want %s", tt.query, tt.exact, body, err, tt.want)
+			}
+		case len(tt.ambiguous) > 0:
+			if !errors.As(err, &ambiguous) {
+				t.Errorf("ResolveObject(%q, %t) error %v, want ambiguous", tt.query, tt.exact, err)
+				continue
+			}
+			var names []string
+			for _, candidate := range ambiguous.Candidates {
+				names = append(names, candidate.Body.Name)
+			}
+			if !sameNames(names, tt.ambiguous) {
+				t.Errorf("ResolveObject(%q, %t) candidates %v, want %v", tt.query, tt.exact, names, tt.ambiguous)
+			}
+		default:
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) {
+				t.Errorf("ResolveObject(%q, %t) = %v, %v, want not found", tt.query, tt.exact, body, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// This file holds a k-d tree over every AstralBody so that nearby and along
+// heading searches don't need to scan every body in the navcomp.  Each node
+// keeps the bounding box of its subtree, which is used to skip whole
+// branches that can't contain a match.
+
+type kdNode struct {
+	body        *AstralBody
+	ordinal     int
+	axis        int
+	left, right *kdNode
+	min, max    Point
+}
+
+type SpatialIndex struct {
+	root *kdNode
+	size int
+}
+
+type indexedBody struct {
+	body    *AstralBody
+	ordinal int
+}
+
+func axisValue(p Point, axis int) float64 {
+	switch axis {
+	case 0:
+		return p.X
+	case 1:
+		return p.Y
+	}
+	return p.Z
+}
+
+// NewSpatialIndex builds an index over bodies.  Query results that aren't
+// ordered by distance are returned in the same order as bodies.
+func NewSpatialIndex(bodies []*AstralBody) *SpatialIndex {
+	items := make([]indexedBody, len(bodies))
+	for ndx, body := range bodies {
+		items[ndx] = indexedBody{body: body, ordinal: ndx}
+	}
+	return &SpatialIndex{root: buildKDTree(items, 0), size: len(bodies)}
+}
+
+func buildKDTree(items []indexedBody, depth int) *kdNode {
+	if len(items) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	sort.Slice(items, func(i, j int) bool {
+		return axisValue(items[i].body.Point, axis) < axisValue(items[j].body.Point, axis)
+	})
+	mid := len(items) / 2
+	node := &kdNode{
+		body:    items[mid].body,
+		ordinal: items[mid].ordinal,
+		axis:    axis,
+		left:    buildKDTree(items[:mid], depth+1),
+		right:   buildKDTree(items[mid+1:], depth+1),
+		min:     items[mid].body.Point,
+		max:     items[mid].body.Point,
+	}
+	for _, child := range []*kdNode{node.left, node.right} {
+		if child == nil {
+			continue
+		}
+		node.min = Point{X: math.Min(node.min.X, child.min.X), Y: math.Min(node.min.Y, child.min.Y), Z: math.Min(node.min.Z, child.min.Z)}
+		node.max = Point{X: math.Max(node.max.X, child.max.X), Y: math.Max(node.max.Y, child.max.Y), Z: math.Max(node.max.Z, child.max.Z)}
+	}
+	return node
+}
+
+func (s *SpatialIndex) Len() int {
+	return s.size
+}
+
+// boxDistance is the distance from p to the nearest point of the bounding
+// box, zero if p is inside it.
+func boxDistance(min, max, p Point) float64 {
+	clamp := func(v, lo, hi float64) float64 { return math.Max(lo, math.Min(hi, v)) }
+	nearest := Point{X: clamp(p.X, min.X, max.X), Y: clamp(p.Y, min.Y, max.Y), Z: clamp(p.Z, min.Z, max.Z)}
+	return p.Distance(nearest)
+}
+
+// Search visits every body for which match returns true, skipping subtrees
+// whose bounding box prune rejects.  Results are in index order.
+func (s *SpatialIndex) Search(prune func(min, max Point) bool, match func(*AstralBody) bool) []*AstralBody {
+	var found []indexedBody
+	var visit func(n *kdNode)
+	visit = func(n *kdNode) {
+		if n == nil || prune(n.min, n.max) {
+			return
+		}
+		if match(n.body) {
+			found = append(found, indexedBody{body: n.body, ordinal: n.ordinal})
+		}
+		visit(n.left)
+		visit(n.right)
+	}
+	visit(s.root)
+	sort.Slice(found, func(i, j int) bool { return found[i].ordinal < found[j].ordinal })
+	bodies := make([]*AstralBody, len(found))
+	for ndx, item := range found {
+		bodies[ndx] = item.body
+	}
+	return bodies
+}
+
+// WithinRadius returns every body within radius parsecs of p.
+func (s *SpatialIndex) WithinRadius(p Point, radius float64) []*AstralBody {
+	return s.Search(
+		func(min, max Point) bool { return boxDistance(min, max, p) > radius },
+		func(body *AstralBody) bool { return body.DistanceToPoint(p) <= radius },
+	)
+}
+
+// AlongLine returns every body within radius parsecs of the line origin +
+// t*dir for minT <= t <= maxT, i.e. inside a cylinder with rounded ends.
+// dir must be a unit vector, and the limits may be infinite for a ray or
+// an unbounded line.
+func (s *SpatialIndex) AlongLine(origin, dir Point, minT, maxT, radius float64) []*AstralBody {
+	return s.Search(
+		func(min, max Point) bool {
+			// Test the box's bounding sphere, which is cheap and never
+			// prunes a box that the cylinder reaches
+			centre := min.Add(max).Scale(0.5)
+			halfDiagonal := max.Sub(min).Norm() / 2
+			return centre.DistanceToLine(origin, dir, minT, maxT) > radius+halfDiagonal
+		},
+		func(body *AstralBody) bool {
+			return body.Point.DistanceToLine(origin, dir, minT, maxT) <= radius
+		},
+	)
+}
+
+type nearestItem struct {
+	item     indexedBody
+	distance float64
+}
+
+// nearestHeap is a max heap on distance, so the furthest of the current k
+// best can be dropped when a closer body is found.
+type nearestHeap []nearestItem
+
+func (h nearestHeap) Len() int { return len(h) }
+func (h nearestHeap) Less(i, j int) bool {
+	if h[i].distance == h[j].distance {
+		return h[i].item.ordinal > h[j].item.ordinal
+	}
+	return h[i].distance > h[j].distance
+}
+func (h nearestHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *nearestHeap) Push(x any)   { *h = append(*h, x.(nearestItem)) }
+func (h *nearestHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// Nearest returns the k bodies closest to p, closest first.
+func (s *SpatialIndex) Nearest(p Point, k int) []*AstralBody {
+	if k <= 0 {
+		return nil
+	}
+	best := &nearestHeap{}
+	var visit func(n *kdNode)
+	visit = func(n *kdNode) {
+		if n == nil {
+			return
+		}
+		if best.Len() == k && boxDistance(n.min, n.max, p) > (*best)[0].distance {
+			return
+		}
+		d := n.body.DistanceToPoint(p)
+		candidate := nearestItem{item: indexedBody{body: n.body, ordinal: n.ordinal}, distance: d}
+		if best.Len() < k {
+			heap.Push(best, candidate)
+		} else if d < (*best)[0].distance || (d == (*best)[0].distance && n.ordinal < (*best)[0].item.ordinal) {
+			(*best)[0] = candidate
+			heap.Fix(best, 0)
+		}
+		// Descend into the side of the split containing p first so the
+		// other side is more likely to be pruned
+		first, second := n.left, n.right
+		if axisValue(p, n.axis) > axisValue(n.body.Point, n.axis) {
+			first, second = second, first
+		}
+		visit(first)
+		visit(second)
+	}
+	visit(s.root)
+	bodies := make([]*AstralBody, best.Len())
+	for ndx := len(bodies) - 1; ndx >= 0; ndx-- {
+		bodies[ndx] = heap.Pop(best).(nearestItem).item.body
+	}
+	return bodies
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// loadTestNavComp loads the shipped atsdata.json into NavComp.
+func loadTestNavComp(tb testing.TB) *ATSData {
+	tb.Helper()
+	atsData, err := ParseATSDataFromFile("atsdata.json")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	NavComp = atsData
+	return atsData
+}
+
+func bodyNames(bodies []AstralBody) []string {
+	names := make([]string, len(bodies))
+	for ndx, body := range bodies {
+		names[ndx] = body.Name
+	}
+	return names
+}
+
+func bodyPointerNames(bodies []*AstralBody) []string {
+	names := make([]string, len(bodies))
+	for ndx, body := range bodies {
+		names[ndx] = body.Name
+	}
+	return names
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for ndx := range a {
+		if a[ndx] != b[ndx] {
+			return false
+		}
+	}
+	return true
+}
+
+// lineDirection is any heading, derived from the body so each query is
+// different.
+func lineDirection(source *AstralBody) Point {
+	return *ProjectHeading(Heading{Yaw: math.Mod(source.X, 360), Pitch: math.Mod(source.Z, 90)}, Point{}, 1)
+}
+
+// spatialQuery is a k-d tree query and the linear scan over FilterBodies it
+// replaces, both giving the names of the bodies found from source.
+type spatialQuery struct {
+	name    string
+	linear  func(source *AstralBody) []string
+	indexed func(source *AstralBody) []string
+	// Bodies at the same distance may come back in a different order
+	unordered bool
+}
+
+func spatialQueries(atsData *ATSData) []spatialQuery {
+	return []spatialQuery{
+		{
+			name: "radius 200",
+			linear: func(source *AstralBody) []string {
+				return bodyNames(atsData.FilterBodies(func(body AstralBody) bool {
+					return source.DistanceToObject(body) <= 200
+				}))
+			},
+			indexed: func(source *AstralBody) []string {
+				return bodyPointerNames(atsData.Index.WithinRadius(source.Point, 200))
+			},
+		},
+		{
+			name: "nearest 20",
+			linear: func(source *AstralBody) []string {
+				bodies := atsData.FilterBodies(func(AstralBody) bool { return true })
+				sort.SliceStable(bodies, func(i, j int) bool {
+					return source.DistanceToObject(bodies[i]) < source.DistanceToObject(bodies[j])
+				})
+				return bodyNames(bodies[:20])
+			},
+			indexed: func(source *AstralBody) []string {
+				return bodyPointerNames(atsData.Index.Nearest(source.Point, 20))
+			},
+			unordered: true,
+		},
+		{
+			name: "line 10 x 1000",
+			linear: func(source *AstralBody) []string {
+				dir := lineDirection(source)
+				return bodyNames(atsData.FilterBodies(func(body AstralBody) bool {
+					_, miss := closestApproach(source.Point, dir, 1000, body.Point)
+					return miss <= 10
+				}))
+			},
+			indexed: func(source *AstralBody) []string {
+				return bodyPointerNames(atsData.Index.AlongLine(source.Point, lineDirection(source), 0, 1000, 10))
+			},
+		},
+	}
+}
+
+func TestSpatialIndexMatchesLinearScan(t *testing.T) {
+	atsData := loadTestNavComp(t)
+	all := atsData.FilterBodies(func(AstralBody) bool { return true })
+	for _, tt := range spatialQueries(atsData) {
+		t.Run(tt.name, func(t *testing.T) {
+			for ndx := range all {
+				linear, indexed := tt.linear(&all[ndx]), tt.indexed(&all[ndx])
+				if tt.unordered {
+					sort.Strings(linear)
+					sort.Strings(indexed)
+				}
+				if !sameNames(linear, indexed) {
+					t.Errorf("from %s: linear scan found %v, index found %v", all[ndx].Name, linear, indexed)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkQuery times the named query as a linear scan and with the index,
+// from each body in turn.
+func benchmarkQuery(b *testing.B, name string) {
+	atsData := loadTestNavComp(b)
+	sources := atsData.FilterBodies(func(AstralBody) bool { return true })
+	for _, query := range spatialQueries(atsData) {
+		if query.name != name {
+			continue
+		}
+		for _, run := range []struct {
+			name  string
+			query func(source *AstralBody) []string
+		}{{"linear", query.linear}, {"index", query.indexed}} {
+			b.Run(run.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					run.query(&sources[i%len(sources)])
+				}
+			})
+		}
+		return
+	}
+	b.Fatalf("no query named %s", name)
+}
+
+func BenchmarkWithinRadius(b *testing.B) {
+	benchmarkQuery(b, "radius 200")
+}
+
+func BenchmarkNearest(b *testing.B) {
+	benchmarkQuery(b, "nearest 20")
+}
+
+func BenchmarkAlongLine(b *testing.B) {
+	benchmarkQuery(b, "line 10 x 1000")
+}
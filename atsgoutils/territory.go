@@ -0,0 +1,282 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+)
+
+// This file answers whose space a point is in.  Each empire's borders are
+// spheres around a centre, and a point is claimed by every empire with a
+// border sphere containing it.  It also predicts where a contact on a
+// straight heading will cross those spheres.
+
+type BorderHit struct {
+	Empire *Empire
+	Border *Border
+	// Distance is from the point to the border's centre
+	Distance float64
+}
+
+// Depth is how far inside the border sphere the point is, negative when it
+// is outside.
+func (b BorderHit) Depth() float64 {
+	return b.Border.Radius - b.Distance
+}
+
+// SurfaceDistance is the distance from the point to the border's surface.
+func (b BorderHit) SurfaceDistance() float64 {
+	return math.Abs(b.Depth())
+}
+
+func (b BorderHit) Contains() bool {
+	return b.Depth() >= 0
+}
+
+type Territory struct {
+	Point Point
+	// Inside lists the borders containing the point, the smallest first
+	Inside []BorderHit
+	// NearestSurface is the border whose surface is closest, whether the
+	// point is inside it or not
+	NearestSurface *BorderHit
+	// NearestEmpire is the border the point is closest to entering, only
+	// set when the point is unclaimed
+	NearestEmpire *BorderHit
+}
+
+func (t Territory) Claimed() bool {
+	return len(t.Inside) > 0
+}
+
+// Territory finds the borders containing p.  Borders with no radius are
+// ignored.
+func (a *ATSData) Territory(p Point) Territory {
+	territory := Territory{Point: p}
+	for ndx := range a.NavcompDB.Empires {
+		empire := &a.NavcompDB.Empires[ndx]
+		for indx := range empire.Borders {
+			border := &empire.Borders[indx]
+			if border.Radius <= 0 {
+				continue
+			}
+			hit := BorderHit{Empire: empire, Border: border, Distance: border.Point.Distance(p)}
+			if hit.Contains() {
+				territory.Inside = append(territory.Inside, hit)
+			}
+			if territory.NearestSurface == nil || hit.SurfaceDistance() < territory.NearestSurface.SurfaceDistance() {
+				nearest := hit
+				territory.NearestSurface = &nearest
+			}
+			if !hit.Contains() && (territory.NearestEmpire == nil || hit.SurfaceDistance() < territory.NearestEmpire.SurfaceDistance()) {
+				nearest := hit
+				territory.NearestEmpire = &nearest
+			}
+		}
+	}
+	sort.SliceStable(territory.Inside, func(i, j int) bool {
+		return territory.Inside[i].Border.Radius < territory.Inside[j].Border.Radius
+	})
+	if territory.Claimed() {
+		territory.NearestEmpire = nil
+	}
+	return territory
+}
+
+func PrintTerritory(label string, t Territory) {
+	fmt.Printf("%s at %s\n", label, FormatPoint(t.Point))
+	if t.Claimed() {
+		tw := table.NewWriter()
+		tw.SetStyle(table.StyleLight)
+		tw.AppendHeader(table.Row{"Empire", "Border", "Radius", "From Centre", "Depth"})
+		for _, hit := range t.Inside {
+			tw.AppendRow(table.Row{hit.Empire.Name, hit.Border.Name, fmt.Sprintf("%.2f", hit.Border.Radius), fmt.Sprintf("%.2f", hit.Distance), fmt.Sprintf("%.2f", hit.Depth())})
+		}
+		fmt.Printf("Inside:\n%s\n", tw.Render())
+	} else {
+		fmt.Println("Unclaimed space")
+	}
+	if hit := t.NearestSurface; hit != nil {
+		side := "outside"
+		if hit.Contains() {
+			side = "inside"
+		}
+		fmt.Printf("Nearest border surface: %s (%s) %.2f parsecs, %s\n", hit.Border.Name, hit.Empire.Name, hit.SurfaceDistance(), side)
+	}
+	if hit := t.NearestEmpire; hit != nil {
+		fmt.Printf("Nearest empire: %s, %.2f parsecs to its %s border\n", hit.Empire.Name, hit.SurfaceDistance(), hit.Border.Name)
+	}
+}
+
+type BorderCrossing struct {
+	Empire *Empire
+	Border *Border
+	// Entering is false when the contact leaves the border
+	Entering bool
+	// Distance is how far along the heading the crossing is, Point is where
+	// it is in GRC and Time is how long until it, in seconds
+	Distance float64
+	Point    Point
+	Time     float64
+}
+
+func (b BorderCrossing) Event() string {
+	if b.Entering {
+		return "Enters"
+	}
+	return "Leaves"
+}
+
+// BorderCrossings finds every border sphere the heading from source enters
+// or leaves within maxDistance parsecs, soonest first, and how long until
+// each at speed.
+func (a *ATSData) BorderCrossings(source Point, heading Heading, speed, maxDistance float64) ([]BorderCrossing, error) {
+	dir := *ProjectHeading(heading, Point{}, 1)
+	contact := AstralBody{Point: source}
+	var crossings []BorderCrossing
+	for ndx := range a.NavcompDB.Empires {
+		empire := &a.NavcompDB.Empires[ndx]
+		for indx := range empire.Borders {
+			border := &empire.Borders[indx]
+			if border.Radius <= 0 {
+				continue
+			}
+			// Solve |source + t*dir - centre| = radius for t
+			toCentre := border.Point.Sub(source)
+			along := toCentre.Dot(dir)
+			disc := along*along - toCentre.Dot(toCentre) + border.Radius*border.Radius
+			if disc <= 0 {
+				continue
+			}
+			root := math.Sqrt(disc)
+			for _, crossing := range []struct {
+				t        float64
+				entering bool
+			}{{along - root, true}, {along + root, false}} {
+				if crossing.t <= 0 || crossing.t > maxDistance {
+					continue
+				}
+				p := *ProjectHeading(heading, source, crossing.t)
+				t, err := contact.TimeToPoint(p, AVG_COCHRANE_DENSITY, speed)
+				if err != nil {
+					return nil, err
+				}
+				crossings = append(crossings, BorderCrossing{
+					Empire:   empire,
+					Border:   border,
+					Entering: crossing.entering,
+					Distance: crossing.t,
+					Point:    p,
+					Time:     t,
+				})
+			}
+		}
+	}
+	sort.SliceStable(crossings, func(i, j int) bool {
+		return crossings[i].Distance < crossings[j].Distance
+	})
+	return crossings, nil
+}
+
+func PrintBorderCrossings(crossings []BorderCrossing) {
+	if len(crossings) == 0 {
+		fmt.Println("No border crossings on this heading")
+		return
+	}
+	tw := table.NewWriter()
+	tw.SetStyle(table.StyleLight)
+	tw.AppendHeader(table.Row{"In", "Event", "Empire", "Border", "Distance", "Point"})
+	for _, crossing := range crossings {
+		in := time.Duration(crossing.Time * 1e9).Truncate(time.Second)
+		tw.AppendRow(table.Row{in, crossing.Event(), crossing.Empire.Name, crossing.Border.Name, fmt.Sprintf("%.2f", crossing.Distance), FormatPoint(crossing.Point)})
+	}
+	fmt.Println(tw.Render())
+}
+
+func runCrossings(cfg *Config, args []string) error {
+	crossingsCmd := flag.NewFlagSet("crossings", flag.ContinueOnError)
+	crossingsContact := AddContactFlags(crossingsCmd)
+	crossingsDist := crossingsCmd.Float64("dist", 1000, "How far along the heading to look for crossings in Parsecs")
+	cfg.AddFlags(crossingsCmd)
+	if err := parseFlags(crossingsCmd, args); err != nil {
+		return err
+	}
+	contact, err := crossingsContact(cfg.Angles)
+	if err != nil {
+		return err
+	}
+	if *crossingsDist <= 0 {
+		return usageErrorf("dist must be greater than 0, received %f", *crossingsDist)
+	}
+	if err := cfg.LoadData(); err != nil {
+		return err
+	}
+	source, err := contact.GRC()
+	if err != nil {
+		return err
+	}
+	PrintTerritory("Contact", NavComp.Territory(source))
+	crossings, err := NavComp.BorderCrossings(source, contact.Heading, contact.Speed, *crossingsDist)
+	if err != nil {
+		return fmt.Errorf("error predicting border crossings: %w", err)
+	}
+	PrintBorderCrossings(crossings)
+	return nil
+}
+
+func runTerritory(cfg *Config, args []string) error {
+	territoryCmd := flag.NewFlagSet("territory", flag.ContinueOnError)
+	territoryBody := territoryCmd.String("body", "", "Body to look up, instead of coordinates")
+	territoryX := territoryCmd.Float64("X", 0, "X Coordinate")
+	territoryY := territoryCmd.Float64("Y", 0, "Y Coordinate")
+	territoryZ := territoryCmd.Float64("Z", 0, "Z Coordinate")
+	territoryFrame := territoryCmd.String("frame", GRC_FRAME, "Frame the coordinates are in, by default GRC")
+	territoryExact := territoryCmd.Bool("exact", false, "Only accept an exact ID, name or alias")
+	cfg.AddFlags(territoryCmd)
+	if err := parseFlags(territoryCmd, args); err != nil {
+		return err
+	}
+	set := make(map[string]bool)
+	territoryCmd.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	var missing []string
+	for _, name := range []string{"X", "Y", "Z"} {
+		if !set[name] {
+			missing = append(missing, "-"+name)
+		}
+	}
+	haveCoords := len(missing) == 0
+	if len(missing) > 0 && len(missing) < 3 {
+		return usageErrorf("expected -X, -Y and -Z, missing %s", joinList(missing))
+	}
+	if (*territoryBody == "") == !haveCoords {
+		return usageErrorf("expected either -body or -X, -Y and -Z")
+	}
+	if err := cfg.LoadData(); err != nil {
+		return err
+	}
+	var label string
+	var p Point
+	if *territoryBody != "" {
+		body, err := cfg.ResolveBody(*territoryBody, *territoryExact)
+		if err != nil {
+			return fmt.Errorf("error looking up %s: %w", *territoryBody, err)
+		}
+		label, p = body.Label(), body.Point
+	} else {
+		p = Point{X: *territoryX, Y: *territoryY, Z: *territoryZ}
+		if *territoryFrame != GRC_FRAME {
+			grc, err := ConvertToGRC(p, *territoryFrame)
+			if err != nil {
+				return err
+			}
+			p = *grc
+		}
+		label = "Point"
+	}
+	PrintTerritory(label, NavComp.Territory(p))
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTerritory(t *testing.T) {
+	atsData := testBorders()
+	tests := []struct {
+		name  string
+		point Point
+		// inside lists the borders containing the point, smallest first
+		inside         []string
+		nearestSurface string
+		nearestEmpire  string
+	}{
+		{name: "both Alpha borders", point: Point{X: 25}, inside: []string{"Alpha Minor", "Alpha Major"}, nearestSurface: "Alpha Minor"},
+		{name: "outer Alpha border", point: Point{X: -80}, inside: []string{"Alpha Major"}, nearestSurface: "Alpha Major"},
+		{name: "on the surface", point: Point{Y: 100}, inside: []string{"Alpha Major"}, nearestSurface: "Alpha Major"},
+		{name: "unclaimed nearer Beta", point: Point{X: 200}, nearestSurface: "Beta", nearestEmpire: "Beta"},
+		{name: "unclaimed nearer Alpha", point: Point{X: 140}, nearestSurface: "Alpha Major", nearestEmpire: "Alpha Major"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			territory := atsData.Territory(tt.point)
+			var inside []string
+			for _, hit := range territory.Inside {
+				inside = append(inside, hit.Border.Name)
+			}
+			if !sameNames(inside, tt.inside) {
+				t.Errorf("inside %v, want %v", inside, tt.inside)
+			}
+			if territory.Claimed() != (len(tt.inside) > 0) {
+				t.Errorf("Claimed() = %t", territory.Claimed())
+			}
+			if territory.NearestSurface == nil || territory.NearestSurface.Border.Name != tt.nearestSurface {
+				t.Errorf("nearest surface %v, want %s", territory.NearestSurface, tt.nearestSurface)
+			}
+			nearestEmpire := ""
+			if territory.NearestEmpire != nil {
+				nearestEmpire = territory.NearestEmpire.Border.Name
+			}
+			if nearestEmpire != tt.nearestEmpire {
+				t.Errorf("nearest empire %q, want %q", nearestEmpire, tt.nearestEmpire)
+			}
+		})
+	}
+}
+
+func TestBorderCrossings(t *testing.T) {
+	atsData := testBorders()
+	tests := []struct {
+		name        string
+		source      Point
+		heading     Heading
+		maxDistance float64
+		// want lists each crossing as its event, border and distance
+		want []string
+		at   []float64
+	}{
+		{
+			name: "through everything", source: Point{X: -200}, maxDistance: 1000,
+			want: []string{"Enters Alpha Major", "Enters Alpha Minor", "Leaves Alpha Minor", "Leaves Alpha Major", "Enters Beta", "Leaves Beta"},
+			at:   []float64{100, 190, 250, 300, 450, 550},
+		},
+		{
+			name: "from inside", source: Point{X: 20}, maxDistance: 1000,
+			want: []string{"Leaves Alpha Minor", "Leaves Alpha Major", "Enters Beta", "Leaves Beta"},
+			at:   []float64{30, 80, 230, 330},
+		},
+		{
+			name: "cut short", source: Point{X: -200}, maxDistance: 200,
+			want: []string{"Enters Alpha Major", "Enters Alpha Minor"},
+			at:   []float64{100, 190},
+		},
+		{name: "heading away", source: Point{X: -200}, heading: Heading{Yaw: 180}, maxDistance: 1000},
+		{name: "passing by", source: Point{X: -200, Y: 150}, maxDistance: 1000},
+		{name: "grazing", source: Point{X: -200, Y: 100}, maxDistance: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crossings, err := atsData.BorderCrossings(tt.source, tt.heading, 8, tt.maxDistance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for ndx, crossing := range crossings {
+				got = append(got, crossing.Event()+" "+crossing.Border.Name)
+				if ndx < len(tt.at) && math.Abs(crossing.Distance-tt.at[ndx]) > 1e-9 {
+					t.Errorf("%s at %g parsecs, want %g", got[ndx], crossing.Distance, tt.at[ndx])
+				}
+				if ndx > 0 && crossing.Time < crossings[ndx-1].Time {
+					t.Errorf("%s is sooner than the crossing before it", got[ndx])
+				}
+			}
+			if !sameNames(got, tt.want) {
+				t.Errorf("crossings %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file fits a contact's track from several sightings, so a pilot
+// doesn't need a heading readout at all.  Each sighting is a time and a
+// position, e.g. "12:00:05 -9330.0 -101.1 -56.7", and the positions are
+// fitted to a straight line at constant speed by least squares.
+
+var sightingTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"15:04:05",
+}
+
+type Sighting struct {
+	Time  time.Time
+	Point Point
+}
+
+// parseSightingTime accepts the layouts above or a number of seconds.
+func parseSightingTime(s string) (time.Time, error) {
+	for _, layout := range sightingTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, 0).Add(time.Duration(seconds * float64(time.Second))), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", s)
+}
+
+// ParseSighting parses "time x y z", where the fields may be separated by
+// spaces or commas and a date and time may be separated by a space.
+func ParseSighting(s string) (Sighting, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	if len(fields) == 5 {
+		fields = append([]string{fields[0] + " " + fields[1]}, fields[2:]...)
+	}
+	if len(fields) != 4 {
+		return Sighting{}, fmt.Errorf("expected time x y z, received %q", s)
+	}
+	t, err := parseSightingTime(fields[0])
+	if err != nil {
+		return Sighting{}, err
+	}
+	var coords [3]float64
+	for ndx, field := range fields[1:] {
+		coords[ndx], err = strconv.ParseFloat(field, 64)
+		if err != nil {
+			return Sighting{}, fmt.Errorf("cannot parse coordinate %q in %q", field, s)
+		}
+	}
+	return Sighting{Time: t, Point: Point{X: coords[0], Y: coords[1], Z: coords[2]}}, nil
+}
+
+// ReadSightings reads one sighting per line, skipping blank lines and
+// lines starting with #.
+func ReadSightings(r io.Reader) ([]Sighting, error) {
+	var sightings []Sighting
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sighting, err := ParseSighting(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		sightings = append(sightings, sighting)
+	}
+	return sightings, scanner.Err()
+}
+
+type Track struct {
+	// Position is where the contact was at the last sighting, according
+	// to the fit, and Velocity is in parsecs per second
+	Position Point
+	Velocity Point
+	Heading  Heading
+	// Warp is the speed that would give Velocity at Cochranes
+	Warp      float64
+	Cochranes float64
+	// RMS is the root mean square distance of the sightings from the fit
+	RMS      float64
+	Duration time.Duration
+}
+
+// WarpForVelocity inverts the velocity used by TimeToPoint, giving the warp
+// speed that covers parsecsPerSecond in space of the given density.
+func WarpForVelocity(parsecsPerSecond, cochranes float64) float64 {
+	return math.Pow(parsecsPerSecond*PARSEC/(cochranes*LIGHTSPEED), 1/3.33)
+}
+
+// FitTrack fits a constant velocity to the sightings by least squares.
+// They needn't be in order, but at least two must be at different times.
+func FitTrack(sightings []Sighting, cochranes float64) (Track, error) {
+	if len(sightings) < 2 {
+		return Track{}, fmt.Errorf("need at least 2 sightings, received %d", len(sightings))
+	}
+	first, last := sightings[0].Time, sightings[0].Time
+	for _, s := range sightings {
+		if s.Time.Before(first) {
+			first = s.Time
+		}
+		if s.Time.After(last) {
+			last = s.Time
+		}
+	}
+	n := float64(len(sightings))
+	var meanT float64
+	var meanP Point
+	for _, s := range sightings {
+		meanT += s.Time.Sub(first).Seconds() / n
+		meanP = meanP.Add(s.Point.Scale(1 / n))
+	}
+	var varT float64
+	var covTP Point
+	for _, s := range sightings {
+		dt := s.Time.Sub(first).Seconds() - meanT
+		varT += dt * dt
+		covTP = covTP.Add(s.Point.Sub(meanP).Scale(dt))
+	}
+	if varT == 0 {
+		return Track{}, fmt.Errorf("sightings must be at different times")
+	}
+	velocity := covTP.Scale(1 / varT)
+	at := func(t time.Time) Point {
+		return meanP.Add(velocity.Scale(t.Sub(first).Seconds() - meanT))
+	}
+	var sumSq float64
+	for _, s := range sightings {
+		sumSq += math.Pow(s.Point.Distance(at(s.Time)), 2)
+	}
+	track := Track{
+		Position:  at(last),
+		Velocity:  velocity,
+		Cochranes: cochranes,
+		RMS:       math.Sqrt(sumSq / n),
+		Duration:  last.Sub(first),
+	}
+	speed := velocity.Norm()
+	if speed == 0 {
+		return track, fmt.Errorf("the contact hasn't moved")
+	}
+	track.Heading = Heading{
+		Yaw:   math.Mod(math.Atan2(velocity.Y, velocity.X)*180/math.Pi+360, 360),
+		Pitch: math.Asin(velocity.Z/speed) * 180 / math.Pi,
+	}
+	track.Warp = WarpForVelocity(speed, cochranes)
+	return track, nil
+}
+
+func (t Track) String() string {
+	return fmt.Sprintf("Position %s heading %s, warp %.2f (%.4f parsecs/s), RMS error %.3f parsecs over %s",
+		FormatPoint(t.Position), FormatHeading(t.Heading), t.Warp, t.Velocity.Norm(), t.RMS, t.Duration)
+}
+
+func runTrack(cfg *Config, args []string) error {
+	trackCmd := flag.NewFlagSet("track", flag.ContinueOnError)
+	var trackSightings stringList
+	trackCmd.Var(&trackSightings, "sighting", "A sighting as \"time x y z\", may be repeated")
+	trackFile := trackCmd.String("file", "", "File of sightings, one \"time x y z\" per line, - for stdin")
+	trackFrame := trackCmd.String("frame", GRC_FRAME, "Frame the sightings are in, by default GRC")
+	trackCochranes := trackCmd.Float64("cochranes", AVG_COCHRANE_DENSITY, "Cochrane density used to turn speed into warp")
+	trackSearch := AddHeadingSearchFlags(trackCmd)
+	trackNumResults := trackCmd.Int("num-results", 10, "Number of results to display")
+	cfg.AddFlags(trackCmd)
+	if err := parseFlags(trackCmd, args); err != nil {
+		return err
+	}
+	search, err := trackSearch()
+	if err != nil {
+		return err
+	}
+	if *trackCochranes <= 0 {
+		return usageErrorf("cochranes must be greater than 0, received %f", *trackCochranes)
+	}
+	var sightings []Sighting
+	for _, s := range append(trackSightings, trackCmd.Args()...) {
+		sighting, err := ParseSighting(s)
+		if err != nil {
+			return usageErrorf("%s", err)
+		}
+		sightings = append(sightings, sighting)
+	}
+	if *trackFile != "" {
+		r := io.Reader(os.Stdin)
+		if *trackFile != "-" {
+			f, err := os.Open(*trackFile)
+			if err != nil {
+				return fmt.Errorf("unable to open %s: %w", *trackFile, err)
+			}
+			defer f.Close()
+			r = f
+		}
+		fromFile, err := ReadSightings(r)
+		if err != nil {
+			return fmt.Errorf("error reading sightings from %s: %w", *trackFile, err)
+		}
+		sightings = append(sightings, fromFile...)
+	}
+	if len(sightings) < 2 {
+		return usageErrorf("expected at least 2 sightings, received %d", len(sightings))
+	}
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+	if *trackFrame != GRC_FRAME {
+		for ndx := range sightings {
+			grc, err := ConvertToGRC(sightings[ndx].Point, *trackFrame)
+			if err != nil {
+				return fmt.Errorf("error converting to GRC: %w", err)
+			}
+			sightings[ndx].Point = *grc
+		}
+	}
+	track, err := FitTrack(sightings, *trackCochranes)
+	if err != nil {
+		return fmt.Errorf("error fitting track: %w", err)
+	}
+	fmt.Println(track)
+	search.Source = track.Position
+	search.Heading = track.Heading
+	search.Speed = track.Warp
+	results, err := search.Rank()
+	if err != nil {
+		return fmt.Errorf("error during findobject: %w", err)
+	}
+	PrintHeadingResults(results, *trackNumResults)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseSighting(t *testing.T) {
+	tests := []struct {
+		line    string
+		seconds float64
+		point   Point
+		err     bool
+	}{
+		{line: "12:00:05 -9330.0 -101.1 -56.7", seconds: 12*3600 + 5, point: Point{X: -9330, Y: -101.1, Z: -56.7}},
+		{line: "12:00:05,1,2,3", seconds: 12*3600 + 5, point: Point{X: 1, Y: 2, Z: 3}},
+		{line: "0000-01-01 00:01:00 1 2 3", seconds: 60, point: Point{X: 1, Y: 2, Z: 3}},
+		{line: "90.5 1 2 3", seconds: 90.5, point: Point{X: 1, Y: 2, Z: 3}},
+		{line: "12:00:05 1 2", err: true},
+		{line: "noon 1 2 3", err: true},
+		{line: "12:00:05 1 two 3", err: true},
+	}
+	for _, tt := range tests {
+		sighting, err := ParseSighting(tt.line)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseSighting(%q) = %v, want an error", tt.line, sighting)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSighting(%q): %s", tt.line, err)
+			continue
+		}
+		// Clock times parse as year 0, numbers as seconds since the epoch
+		base := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+		if tt.line == "90.5 1 2 3" {
+			base = time.Unix(0, 0)
+		}
+		if got := sighting.Time.Sub(base).Seconds(); got != tt.seconds || sighting.Point != tt.point {
+			t.Errorf("ParseSighting(%q) = %gs %v, want %gs %v", tt.line, got, sighting.Point, tt.seconds, tt.point)
+		}
+	}
+}
+
+func TestWarpForVelocity(t *testing.T) {
+	for _, warp := range []float64{1, 4.5, 8, 9.9} {
+		for _, cochranes := range []float64{AVG_COCHRANE_DENSITY, 1000, 1500} {
+			velocity := math.Pow(warp, 3.33) * cochranes * LIGHTSPEED / PARSEC
+			if got := WarpForVelocity(velocity, cochranes); math.Abs(got-warp) > 1e-9 {
+				t.Errorf("WarpForVelocity at warp %g in %g cochranes = %g", warp, cochranes, got)
+			}
+		}
+	}
+}
+
+// sightingsAlong are sightings every 10 seconds of a contact leaving the
+// origin at velocity, each offset by the matching jitter.
+func sightingsAlong(velocity Point, jitter ...Point) []Sighting {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sightings := make([]Sighting, len(jitter))
+	for ndx, offset := range jitter {
+		seconds := float64(10 * ndx)
+		sightings[ndx] = Sighting{
+			Time:  start.Add(time.Duration(seconds) * time.Second),
+			Point: velocity.Scale(seconds).Add(offset),
+		}
+	}
+	return sightings
+}
+
+func TestFitTrack(t *testing.T) {
+	velocity := ProjectHeading(Heading{Yaw: 45, Pitch: 10}, Point{}, 0.5)
+	// The jitter is symmetric about the middle, so doesn't change the fit
+	noisy := sightingsAlong(*velocity, Point{Y: 0.1}, Point{Y: -0.1}, Point{Y: -0.1}, Point{Y: 0.1})
+	tests := []struct {
+		name      string
+		sightings []Sighting
+		heading   Heading
+		// rms is the expected RMS error, or -1 for an error
+		rms float64
+	}{
+		{name: "exact", sightings: sightingsAlong(*velocity, Point{}, Point{}, Point{}), heading: Heading{Yaw: 45, Pitch: 10}},
+		{name: "out of order", sightings: []Sighting{noisy[2], noisy[0], noisy[3], noisy[1]}, heading: Heading{Yaw: 45, Pitch: 10}, rms: 0.1},
+		{name: "one sighting", sightings: sightingsAlong(*velocity, Point{}), rms: -1},
+		{name: "same time", sightings: []Sighting{noisy[0], noisy[0]}, rms: -1},
+		{name: "stationary", sightings: sightingsAlong(Point{}, Point{}, Point{}), rms: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track, err := FitTrack(tt.sightings, AVG_COCHRANE_DENSITY)
+			if tt.rms < 0 {
+				if err == nil {
+					t.Errorf("FitTrack() = %s, want an error", track)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(track.Heading.Yaw-tt.heading.Yaw) > 0.5 || math.Abs(track.Heading.Pitch-tt.heading.Pitch) > 0.5 {
+				t.Errorf("heading %s, want %s", FormatHeading(track.Heading), FormatHeading(tt.heading))
+			}
+			if math.Abs(track.Velocity.Norm()-0.5) > 1e-3 {
+				t.Errorf("speed %g parsecs/s, want 0.5", track.Velocity.Norm())
+			}
+			if math.Abs(track.RMS-tt.rms) > 1e-3 {
+				t.Errorf("RMS %g, want %g", track.RMS, tt.rms)
+			}
+			if want := WarpForVelocity(0.5, AVG_COCHRANE_DENSITY); math.Abs(track.Warp-want) > 1e-3 {
+				t.Errorf("warp %g, want %g", track.Warp, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jedib0t/go-pretty/table"
+)
+
+// This file plans trade runs: buy a commodity at one market, fly the best
+// route to another and sell it there.  Runs are ranked by profit per hour,
+// counting the time through any gate as well as the flying time.
+
+type TradeRun struct {
+	Commodity string
+	From, To  *AstralBody
+	BuyPrice  float64
+	SellPrice float64
+	Units     int
+	Route     *Route
+	// Deadhead is the route from the ship's current position to From, nil
+	// if no source was given
+	Deadhead *Route
+	Time     time.Duration
+}
+
+func (t TradeRun) Profit() float64 {
+	return (t.SellPrice - t.BuyPrice) * float64(t.Units)
+}
+
+// ProfitPerHour is infinite for runs between bodies at the same position.
+func (t TradeRun) ProfitPerHour() float64 {
+	if t.Time <= 0 {
+		return math.Inf(1)
+	}
+	return t.Profit() / t.Time.Hours()
+}
+
+// GateJumps is the number of gate transits along the route.
+func (r *Route) GateJumps() int {
+	if r.IsDirect {
+		return 0
+	}
+	jumps := 1
+	for _, stop := range r.Stops {
+		jumps += stop.GateJumps()
+	}
+	return jumps
+}
+
+type TradeOptions struct {
+	Cargo int
+	// Budget limits how many units can be bought, zero for no limit
+	Budget   float64
+	Speed    float64
+	GateTime time.Duration
+	// Source, when set, adds the time to fly to each run's first market
+	Source *AstralBody
+	Filter func(AstralBody) bool
+}
+
+func (o TradeOptions) routeTime(route *Route) time.Duration {
+	return route.TimeToExecute(o.Speed) + time.Duration(route.GateJumps())*o.GateTime
+}
+
+// PlanTrades returns every profitable run between markets passing the
+// filter, most profitable per hour first.
+func PlanTrades(opts TradeOptions) ([]TradeRun, error) {
+	var markets []*AstralBody
+	for _, loc := range NavComp.bodyLocations() {
+		if loc.Body.Market != MARKET_NONE && (opts.Filter == nil || opts.Filter(*loc.Body)) {
+			markets = append(markets, loc.Body)
+		}
+	}
+	prices := make(map[*AstralBody]map[string]Price, len(markets))
+	for _, body := range markets {
+		prices[body] = Markets.Prices(body)
+	}
+	var runs []TradeRun
+	for _, from := range markets {
+		var deadhead *Route
+		if opts.Source != nil {
+			route, err := BestRoute(opts.Source, from)
+			if err != nil {
+				return nil, fmt.Errorf("error routing to %s: %w", from.Name, err)
+			}
+			deadhead = route
+		}
+		for _, to := range markets {
+			if from == to {
+				continue
+			}
+			var route *Route
+			for commodity, buy := range prices[from] {
+				sell, ok := prices[to][commodity]
+				if buy.Buy <= 0 || !ok || sell.Sell <= buy.Buy {
+					continue
+				}
+				units := opts.Cargo
+				if opts.Budget > 0 {
+					units = min(units, int(opts.Budget/buy.Buy))
+				}
+				if units <= 0 {
+					continue
+				}
+				if route == nil {
+					r, err := BestRoute(from, to)
+					if err != nil {
+						return nil, fmt.Errorf("error routing from %s to %s: %w", from.Name, to.Name, err)
+					}
+					route = r
+				}
+				run := TradeRun{
+					Commodity: commodity,
+					From:      from,
+					To:        to,
+					BuyPrice:  buy.Buy,
+					SellPrice: sell.Sell,
+					Units:     units,
+					Route:     route,
+					Deadhead:  deadhead,
+					Time:      opts.routeTime(route),
+				}
+				if deadhead != nil {
+					run.Time += opts.routeTime(deadhead)
+				}
+				runs = append(runs, run)
+			}
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].ProfitPerHour() != runs[j].ProfitPerHour() {
+			return runs[i].ProfitPerHour() > runs[j].ProfitPerHour()
+		}
+		if runs[i].Profit() != runs[j].Profit() {
+			return runs[i].Profit() > runs[j].Profit()
+		}
+		return runs[i].Commodity < runs[j].Commodity
+	})
+	return runs, nil
+}
+
+func PrintTrades(runs []TradeRun, numResults int) {
+	t := table.NewWriter()
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"#", "Commodity", "Buy At", "Price", "Sell At", "Price", "Units", "Profit", "Route", "Time", "Profit/h"})
+	for ndx, run := range runs {
+		if ndx >= numResults {
+			break
+		}
+		route := "DIRECT"
+		if jumps := run.Route.GateJumps(); jumps > 0 {
+			route = fmt.Sprintf("GATED x%d", jumps)
+		}
+		perHour := "instant"
+		if !math.IsInf(run.ProfitPerHour(), 1) {
+			perHour = fmt.Sprintf("%.2f", run.ProfitPerHour())
+		}
+		t.AppendRow(table.Row{
+			ndx + 1, run.Commodity,
+			run.From.Label(), fmt.Sprintf("%.2f", run.BuyPrice),
+			run.To.Label(), fmt.Sprintf("%.2f", run.SellPrice),
+			run.Units, fmt.Sprintf("%.2f", run.Profit()),
+			route, run.Time.Truncate(time.Second), perHour,
+		})
+	}
+	fmt.Println(t.Render())
+}
+
+func runTrade(cfg *Config, args []string) error {
+	tradeCmd := flag.NewFlagSet("trade", flag.ContinueOnError)
+	tradeCargo := tradeCmd.Int("cargo", 0, "Cargo capacity in units")
+	tradeBudget := tradeCmd.Float64("budget", 0, "Credits available to buy cargo, 0 for no limit")
+	tradeSpeed := tradeCmd.Float64("speed", 22, "Speed in knots")
+	tradeGateTime := tradeCmd.Duration("gate-time", 0, "Time taken to transit each gate")
+	tradeSource := tradeCmd.String("source", "", "Where the ship is now, adds the time to reach the first market")
+	tradeNear := tradeCmd.String("near", "", "Only trade between markets within -range of this body")
+	tradeRange := tradeCmd.Float64("range", 500, "Range from -near in parsecs")
+	tradeNumResults := tradeCmd.Int("num-results", 10, "Number of results to display")
+	tradeExact := tradeCmd.Bool("exact", false, "Only accept exact IDs, names or aliases")
+	tradeFilter := AddBodyFilterFlags(tradeCmd)
+	cfg.AddFlags(tradeCmd)
+	if err := parseFlags(tradeCmd, args); err != nil {
+		return err
+	}
+	bodyFilter, err := tradeFilter()
+	if err != nil {
+		return err
+	}
+	if *tradeCargo <= 0 {
+		return usageErrorf("cargo must be greater than 0, received %d", *tradeCargo)
+	}
+	if *tradeSpeed <= 0 {
+		return usageErrorf("speed must be greater than 0, received %f", *tradeSpeed)
+	}
+	if err := cfg.Load(); err != nil {
+		return err
+	}
+	if err := cfg.LoadMarket(true); err != nil {
+		return err
+	}
+	opts := TradeOptions{
+		Cargo:    *tradeCargo,
+		Budget:   *tradeBudget,
+		Speed:    *tradeSpeed,
+		GateTime: *tradeGateTime,
+		Filter:   bodyFilter.Matches,
+	}
+	if *tradeSource != "" {
+		opts.Source, err = cfg.ResolveBody(*tradeSource, *tradeExact)
+		if err != nil {
+			return fmt.Errorf("error looking up source %s: %w", *tradeSource, err)
+		}
+	}
+	if *tradeNear != "" {
+		near, err := cfg.ResolveBody(*tradeNear, *tradeExact)
+		if err != nil {
+			return fmt.Errorf("error looking up %s: %w", *tradeNear, err)
+		}
+		opts.Filter = func(body AstralBody) bool {
+			return bodyFilter.Matches(body) && near.DistanceToObject(body) <= *tradeRange
+		}
+	}
+	runs, err := PlanTrades(opts)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no profitable trades found")
+	}
+	PrintTrades(runs, *tradeNumResults)
+	return nil
+}
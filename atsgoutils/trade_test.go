@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRouteTime(t *testing.T) {
+	a := &AstralBody{Name: "A", Cochranes: 1000}
+	b := &AstralBody{Name: "B", Cochranes: 1000}
+	direct := &Route{Source: a, Target: b, IsDirect: true, Distance: 100}
+	gated := &Route{Source: a, Target: b, Stops: []*Route{direct, direct}}
+	twice := &Route{Source: a, Target: b, Stops: []*Route{gated, direct}}
+	tests := []struct {
+		name     string
+		route    *Route
+		gateTime time.Duration
+		jumps    int
+		want     time.Duration
+	}{
+		{name: "direct", route: direct, gateTime: time.Hour, want: warpTime(100, 1000, 9)},
+		{name: "gated without gate time", route: gated, jumps: 1, want: 2 * warpTime(100, 1000, 9)},
+		{name: "gated", route: gated, gateTime: 2 * time.Minute, jumps: 1, want: 2*warpTime(100, 1000, 9) + 2*time.Minute},
+		{name: "two gates", route: twice, gateTime: 2 * time.Minute, jumps: 2, want: 3*warpTime(100, 1000, 9) + 4*time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if jumps := tt.route.GateJumps(); jumps != tt.jumps {
+				t.Errorf("GateJumps() = %d, want %d", jumps, tt.jumps)
+			}
+			got := TradeOptions{Speed: 9, GateTime: tt.gateTime}.routeTime(tt.route)
+			if diff := got - tt.want; diff < -time.Microsecond || diff > time.Microsecond {
+				t.Errorf("routeTime() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanTrades(t *testing.T) {
+	testMarkets(t)
+	city := findBody(t, NavComp, "City")
+	// Food is the only thing worth carrying: Farm to Mill makes 26 a unit
+	// over 100 parsecs, City to Mill 18 over 130 through the gates and Farm
+	// to City 6 over 30 through the gates
+	tests := []struct {
+		name string
+		opts TradeOptions
+		// want is each run's from and to, in order
+		want [][2]string
+	}{
+		{
+			name: "profit per hour",
+			opts: TradeOptions{Cargo: 100, Speed: 9},
+			want: [][2]string{{"Farm", "Mill"}, {"Farm", "City"}, {"City", "Mill"}},
+		},
+		{
+			name: "slow gates",
+			opts: TradeOptions{Cargo: 100, Speed: 9, GateTime: 24 * time.Hour},
+			want: [][2]string{{"Farm", "Mill"}, {"City", "Mill"}, {"Farm", "City"}},
+		},
+		{
+			// Reaching Farm from City takes another 30 parsecs
+			name: "source",
+			opts: TradeOptions{Cargo: 100, Speed: 9, Source: city},
+			want: [][2]string{{"Farm", "Mill"}, {"City", "Mill"}, {"Farm", "City"}},
+		},
+		{
+			name: "filter",
+			opts: TradeOptions{Cargo: 100, Speed: 9, Filter: func(body AstralBody) bool { return body.Name != "Mill" }},
+			want: [][2]string{{"Farm", "City"}},
+		},
+		{
+			name: "budget too small",
+			opts: TradeOptions{Cargo: 100, Speed: 9, Budget: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, err := PlanTrades(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(runs) != len(tt.want) {
+				t.Fatalf("%d runs, want %d", len(runs), len(tt.want))
+			}
+			for ndx, run := range runs {
+				if run.From.Name != tt.want[ndx][0] || run.To.Name != tt.want[ndx][1] || run.Commodity != "Food" {
+					t.Errorf("run %d is %s from %s to %s, want %s to %s", ndx, run.Commodity, run.From.Name, run.To.Name, tt.want[ndx][0], tt.want[ndx][1])
+				}
+				want := tt.opts.routeTime(run.Route)
+				if tt.opts.Source != nil {
+					if run.Deadhead == nil || run.Deadhead.Source != tt.opts.Source {
+						t.Fatalf("run %d has no deadhead from the source", ndx)
+					}
+					want += tt.opts.routeTime(run.Deadhead)
+				} else if run.Deadhead != nil {
+					t.Errorf("run %d has a deadhead without a source", ndx)
+				}
+				if run.Time != want {
+					t.Errorf("run %d takes %s, want %s", ndx, run.Time, want)
+				}
+			}
+		})
+	}
+
+	runs, err := PlanTrades(TradeOptions{Cargo: 100, Speed: 9, Budget: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Food is 5 at Farm and 12 at City
+	for _, run := range runs {
+		want := map[string]int{"Farm": 20, "City": 8}[run.From.Name]
+		if run.Units != want {
+			t.Errorf("%d units from %s on a budget of 100, want %d", run.Units, run.From.Name, want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This file loads frames the user defines themselves, such as fleet rally
+// points and anomalies, from their config file:
+//
+//	{
+//		"frames": [
+//			{"name": "Rally Alpha", "x": 12.5, "y": -40, "z": 3, "parent": "Federation"},
+//			{"name": "Nebula Edge", "x": 0, "y": 20, "z": 0, "parent": "Rally Alpha"}
+//		]
+//	}
+//
+// Coordinates are in the parent frame, GRC when no parent is given, and the
+// parent may be a border or another user frame.  Each frame is also a
+// bookmark, so its name can be used wherever a body is expected.
+
+const (
+	CONFIG_FILENAME = "config.json"
+	CONFIG_ENV_VAR  = "ATSGOUTILS_CONFIG"
+	KIND_BOOKMARKS  = "bookmarks"
+)
+
+type FrameDef struct {
+	Name        string  `json:"name"`
+	Description string  `json:"desc,omitempty"`
+	X           float64 `json:"x"`
+	Y           float64 `json:"y"`
+	Z           float64 `json:"z"`
+	Parent      string  `json:"parent,omitempty"`
+}
+
+type UserConfig struct {
+	Frames []FrameDef      `json:"frames"`
+	Alerts []AlertTemplate `json:"alerts"`
+}
+
+// ResolveConfigPath returns the user config file to load, or "" if none
+// was given and the default doesn't exist.
+func (c *Config) ResolveConfigPath() string {
+	if c.ConfigPath != "" {
+		return c.ConfigPath
+	}
+	if env := os.Getenv(CONFIG_ENV_VAR); env != "" {
+		return env
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	configPath := filepath.Join(configDir, APP_NAME, CONFIG_FILENAME)
+	if !fileExists(configPath) {
+		return ""
+	}
+	return configPath
+}
+
+func ParseUserConfigFromFile(filename string) (*UserConfig, error) {
+	var u UserConfig
+	rawBytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", filename, err)
+	}
+	err = json.Unmarshal(rawBytes, &u)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling json: %w", err)
+	}
+	return &u, nil
+}
+
+// AddUserFrames resolves each definition's parent and adds it to the
+// frames ResolveFrame searches.  Definitions may be in any order, but a
+// parent that is missing, ambiguous or part of a cycle is an error.
+func (a *ATSData) AddUserFrames(defs []FrameDef) error {
+	pending := make([]FrameDef, 0, len(defs))
+	for _, def := range defs {
+		if strings.TrimSpace(def.Name) == "" {
+			return errors.New("user frame with no name")
+		}
+		pending = append(pending, def)
+	}
+	for len(pending) > 0 {
+		var waiting []FrameDef
+		for _, def := range pending {
+			parent := Frame{Name: GRC_FRAME}
+			if def.Parent != "" && !strings.EqualFold(def.Parent, GRC_FRAME) {
+				if waitingFor(pending, def.Parent) {
+					waiting = append(waiting, def)
+					continue
+				}
+				p, err := a.ResolveFrame(def.Parent)
+				if err != nil {
+					return fmt.Errorf("user frame %s: %w", def.Name, err)
+				}
+				parent = p
+			}
+			a.userFrames = append(a.userFrames, Frame{
+				Name:   def.Name,
+				Parent: parent.Name,
+				Origin: parent.ToGRC(Point{X: def.X, Y: def.Y, Z: def.Z}),
+				User:   true,
+			})
+		}
+		if len(waiting) == len(pending) {
+			names := make([]string, len(waiting))
+			for ndx, def := range waiting {
+				names[ndx] = def.Name
+			}
+			return fmt.Errorf("user frames %s have parents that form a cycle", strings.Join(names, ", "))
+		}
+		pending = waiting
+	}
+	return nil
+}
+
+// waitingFor reports whether name is one of the user frames still to be
+// added, so frames naming it as a parent must wait for it.
+func waitingFor(pending []FrameDef, name string) bool {
+	for _, def := range pending {
+		if strings.EqualFold(def.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindBookmark returns a body standing in for the user frame named name,
+// which must match exactly.  Bookmarks aren't in the index and have no
+// empire, and routes to them aren't cached as the user may move them.
+func (a *ATSData) FindBookmark(name string) (*AstralBody, bool) {
+	for _, frame := range a.userFrames {
+		if strings.EqualFold(frame.Name, lookupKey(name)) {
+			return &AstralBody{
+				ID:     DeriveBodyID(KIND_BOOKMARKS, frame.Name),
+				Name:   frame.Name,
+				X:      frame.Origin.X,
+				Y:      frame.Origin.Y,
+				Z:      frame.Origin.Z,
+				Market: MARKET_NONE,
+				Point:  frame.Origin,
+				Kind:   KIND_BOOKMARKS,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// LoadUserConfig reads the user's frames into NavComp.
+func (c *Config) LoadUserConfig() error {
+	configPath := c.ResolveConfigPath()
+	if configPath == "" {
+		return nil
+	}
+	userConfig, err := ParseUserConfigFromFile(configPath)
+	if err != nil {
+		return fmt.Errorf("error parsing user config from file %s: %w", configPath, err)
+	}
+	if err := NavComp.AddUserFrames(userConfig.Frames); err != nil {
+		return fmt.Errorf("error in user config %s: %w", configPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// testBorders is two empires on the X axis, Alpha with a small border
+// inside its large one, and Beta 300 parsecs away.
+func testBorders() *ATSData {
+	borders := func(borders ...Border) []Border {
+		for ndx := range borders {
+			borders[ndx].CreatePoint()
+		}
+		return borders
+	}
+	return &ATSData{NavcompDB: NavcompDB{Empires: []Empire{
+		{Name: "Alpha", Borders: borders(
+			Border{Name: "Alpha Major", Radius: 100},
+			Border{Name: "Alpha Minor", X: 20, Radius: 30},
+			Border{Name: "Alpha Unset"},
+		)},
+		{Name: "Beta", Borders: borders(Border{Name: "Beta", X: 300, Radius: 50})},
+	}}}
+}
+
+func TestAddUserFrames(t *testing.T) {
+	tests := []struct {
+		name string
+		defs []FrameDef
+		// origins are the GRC origins of the frames added, or err a
+		// substring of the expected error
+		origins map[string]Point
+		err     string
+	}{
+		{
+			name:    "in GRC",
+			defs:    []FrameDef{{Name: "Rally", X: 1, Y: 2, Z: 3}, {Name: "Rally GRC", X: 4, Parent: "grc"}},
+			origins: map[string]Point{"Rally": {X: 1, Y: 2, Z: 3}, "Rally GRC": {X: 4}},
+		},
+		{
+			name:    "in a border",
+			defs:    []FrameDef{{Name: "Rally", X: 1, Parent: "alpha minor"}},
+			origins: map[string]Point{"Rally": {X: 21}},
+		},
+		{
+			name: "chained out of order",
+			defs: []FrameDef{
+				{Name: "Edge", Y: 20, Parent: "Rally"},
+				{Name: "Far Edge", Z: 5, Parent: "Edge"},
+				{Name: "Rally", X: 1, Parent: "Alpha Minor"},
+			},
+			origins: map[string]Point{"Rally": {X: 21}, "Edge": {X: 21, Y: 20}, "Far Edge": {X: 21, Y: 20, Z: 5}},
+		},
+		{name: "own parent", defs: []FrameDef{{Name: "Loop", Parent: "loop"}}, err: "Loop have parents that form a cycle"},
+		{
+			name: "cycle",
+			defs: []FrameDef{{Name: "Rally"}, {Name: "A", Parent: "B"}, {Name: "B", Parent: "A"}},
+			err:  "A, B have parents that form a cycle",
+		},
+		{name: "missing parent", defs: []FrameDef{{Name: "Rally", Parent: "Gamma"}}, err: "user frame Rally"},
+		{name: "no name", defs: []FrameDef{{Name: " "}}, err: "no name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atsData := testBorders()
+			err := atsData.AddUserFrames(tt.defs)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("AddUserFrames() error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(atsData.userFrames) != len(tt.origins) {
+				t.Errorf("added %d frames, want %d", len(atsData.userFrames), len(tt.origins))
+			}
+			for name, origin := range tt.origins {
+				frame, err := atsData.ResolveFrame(name)
+				if err != nil {
+					t.Errorf("ResolveFrame(%q): %s", name, err)
+					continue
+				}
+				if !frame.User || frame.Origin.Distance(origin) > 1e-9 {
+					t.Errorf("frame %s, want a user frame at %v", frame, origin)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// This file checks a navcomp dataset for mistakes that unmarshalling alone
+// won't catch, such as duplicate names or aliases that clash with other
+// bodies.
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "ERROR"
+	}
+	return "WARNING"
+}
+
+type ValidationIssue struct {
+	Severity Severity
+	Location string
+	Message  string
+}
+
+func (v ValidationIssue) String() string {
+	return fmt.Sprintf("%-7s %s: %s", v.Severity, v.Location, v.Message)
+}
+
+func ValidateATSData(a *ATSData) []ValidationIssue {
+	var issues []ValidationIssue
+	addIssue := func(severity Severity, location, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Severity: severity, Location: location, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, empire := range a.NavcompDB.Empires {
+		if empire.Name == "" {
+			addIssue(SeverityError, "empires", "empire with no name")
+		}
+		for ndx, border := range empire.Borders {
+			location := fmt.Sprintf("%s/borders[%d] %q", empire.Name, ndx, border.Name)
+			if border.Name == "" {
+				addIssue(SeverityError, location, "border has no name")
+			}
+			if border.Radius <= 0 {
+				addIssue(SeverityWarning, location, "border radius %.2f is not positive", border.Radius)
+			}
+		}
+	}
+
+	locations := a.bodyLocations()
+	byName := make(map[string][]bodyLocation)
+	for _, loc := range locations {
+		body := loc.Body
+		if strings.TrimSpace(body.Name) == "" {
+			addIssue(SeverityError, loc.String(), "body has no name")
+			continue
+		}
+		byName[strings.ToLower(body.Name)] = append(byName[strings.ToLower(body.Name)], loc)
+		if body.X == 0 && body.Y == 0 && body.Z == 0 {
+			addIssue(SeverityError, loc.String(), "coordinates are all zero")
+		}
+		if body.Cochranes == 0 {
+			addIssue(SeverityWarning, loc.String(), "missing cochranes, %.2f will be assumed", AVG_COCHRANE_DENSITY)
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dupes := byName[name]
+		if len(dupes) < 2 {
+			continue
+		}
+		for _, dupe := range dupes[1:] {
+			addIssue(SeverityError, dupe.String(), "duplicate name, first defined at %s", dupes[0])
+		}
+	}
+
+	// An exact name always resolves, but a partial name that would have
+	// found this body may now be ambiguous
+	for ndx, loc := range locations {
+		name := strings.ToLower(loc.Body.Name)
+		if name == "" {
+			continue
+		}
+		for ondx, other := range locations {
+			otherName := strings.ToLower(other.Body.Name)
+			if ondx == ndx || otherName == name || !strings.Contains(otherName, name) {
+				continue
+			}
+			addIssue(SeverityWarning, loc.String(), "name is a substring of %s", other)
+		}
+	}
+
+	// IDs and aliases must each pick out exactly one body for FindExact
+	claims := make(map[string]bodyLocation)
+	claim := func(loc bodyLocation, key, what string) {
+		key = lookupKey(key)
+		if first, ok := claims[key]; ok && first.Body != loc.Body {
+			addIssue(SeverityError, loc.String(), "%s %q is already used by %s", what, key, first)
+			return
+		}
+		claims[key] = loc
+	}
+	for _, loc := range locations {
+		claim(loc, loc.Body.ID, "id")
+	}
+	for _, loc := range locations {
+		for _, alias := range loc.Body.AllAliases() {
+			if other, ok := byName[lookupKey(alias)]; ok && other[0].Body != loc.Body {
+				addIssue(SeverityError, loc.String(), "alias %q is the name of %s", alias, other[0])
+				continue
+			}
+			claim(loc, alias, "alias")
+		}
+	}
+	aliasIDs := make([]string, 0, len(a.NavcompDB.Aliases))
+	for id := range a.NavcompDB.Aliases {
+		aliasIDs = append(aliasIDs, id)
+	}
+	sort.Strings(aliasIDs)
+	for _, id := range aliasIDs {
+		if _, ok := a.FindByID(id); !ok {
+			addIssue(SeverityError, "aliases", "alias table entry %q does not match any body id", id)
+		}
+	}
+
+	for _, gate := range GateNames {
+		if _, ok := a.FindExact(gate); !ok {
+			addIssue(SeverityError, "gates", "gate %q does not match any body", gate)
+		}
+	}
+	return issues
+}
+
+func runValidate(cfg *Config, args []string) error {
+	validateCmd := flag.NewFlagSet("validate", flag.ContinueOnError)
+	validateStrict := validateCmd.Bool("strict", false, "Treat warnings as errors")
+	cfg.AddFlags(validateCmd)
+	if err := parseFlags(validateCmd, args); err != nil {
+		return err
+	}
+	if validateCmd.NArg() > 1 {
+		return usageErrorf("validate takes at most one data file, received %d", validateCmd.NArg())
+	}
+	if validateCmd.NArg() == 1 {
+		cfg.DataPath = validateCmd.Arg(0)
+	}
+	// The file itself is validated, not the file with overlays on top
+	atsData, err := cfg.LoadBaseData()
+	if err != nil {
+		return err
+	}
+	issues := ValidateATSData(atsData)
+	numErrors, numWarnings := 0, 0
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if issue.Severity == SeverityError {
+			numErrors++
+		} else {
+			numWarnings++
+		}
+	}
+	fmt.Printf("%d errors, %d warnings\n", numErrors, numWarnings)
+	if numErrors > 0 || (*validateStrict && numWarnings > 0) {
+		return fmt.Errorf("validation failed")
+	}
+	return nil
+}
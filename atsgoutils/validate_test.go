@@ -0,0 +1,147 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// testEmpire returns the named empire, failing the test if there isn't one.
+func testEmpire(t *testing.T, a *ATSData, name string) *Empire {
+	t.Helper()
+	empire := a.findEmpire(name)
+	if empire == nil {
+		t.Fatalf("no empire %s", name)
+	}
+	return empire
+}
+
+func TestValidateATSData(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, a *ATSData)
+		// want lists the new issues, each as its severity and part of its
+		// message
+		want []string
+	}{
+		{
+			name: "border without a radius",
+			change: func(t *testing.T, a *ATSData) {
+				testEmpire(t, a, "Federation").Borders[0].Radius = 0
+			},
+			want: []string{"WARNING border radius 0.00 is not positive"},
+		},
+		{
+			name: "body at the origin",
+			change: func(t *testing.T, a *ATSData) {
+				empire := testEmpire(t, a, "Federation")
+				empire.Planets = append(empire.Planets, AstralBody{Name: "Nowhere", Cochranes: 1500})
+			},
+			want: []string{"ERROR coordinates are all zero"},
+		},
+		{
+			name: "missing cochranes",
+			change: func(t *testing.T, a *ATSData) {
+				empire := testEmpire(t, a, "Federation")
+				empire.Planets = append(empire.Planets, AstralBody{Name: "Thin Air", X: 1, Y: 2, Z: 3})
+			},
+			want: []string{"WARNING missing cochranes"},
+		},
+		{
+			name: "duplicate name",
+			change: func(t *testing.T, a *ATSData) {
+				empire := testEmpire(t, a, "Klingon")
+				empire.Planets = append(empire.Planets, AstralBody{Name: "vulcan", X: 1, Y: 2, Z: 3, Cochranes: 1500})
+			},
+			want: []string{"ERROR duplicate name, first defined at Federation/planets"},
+		},
+		{
+			name: "name inside another",
+			change: func(t *testing.T, a *ATSData) {
+				empire := testEmpire(t, a, "Federation")
+				empire.Planets = append(empire.Planets, AstralBody{Name: "Vulca", X: 1, Y: 2, Z: 3, Cochranes: 1500})
+			},
+			want: []string{`WARNING name is a substring of Federation/planets`},
+		},
+		{
+			name: "alias naming another body",
+			change: func(t *testing.T, a *ATSData) {
+				body, _ := a.FindExact("Vulcan")
+				body.Aliases = append(body.Aliases, "Andor")
+			},
+			want: []string{`ERROR alias "Andor" is the name of`},
+		},
+		{
+			name: "shared ID",
+			change: func(t *testing.T, a *ATSData) {
+				body, _ := a.FindExact("Vulcan")
+				body.ID = "federation/andor"
+			},
+			want: []string{`ERROR id "federation/andor" is already used by`},
+		},
+		{
+			name: "alias table for a missing body",
+			change: func(t *testing.T, a *ATSData) {
+				a.NavcompDB.Aliases = map[string][]string{"federation/nowhere": {"Nowhere"}}
+			},
+			want: []string{`ERROR alias table entry "federation/nowhere" does not match any body id`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atsData := loadTestNavComp(t)
+			before := make(map[string]bool)
+			for _, issue := range ValidateATSData(atsData) {
+				before[issue.String()] = true
+			}
+			tt.change(t, atsData)
+			atsData.IndexBodies()
+			var added []ValidationIssue
+			for _, issue := range ValidateATSData(atsData) {
+				if !before[issue.String()] {
+					added = append(added, issue)
+				}
+			}
+			if len(added) != len(tt.want) {
+				t.Fatalf("new issues %v, want %d", added, len(tt.want))
+			}
+			for ndx, want := range tt.want {
+				severity, message, _ := strings.Cut(want, " ")
+				if added[ndx].Severity.String() != severity || !strings.Contains(added[ndx].Message, message) {
+					t.Errorf("issue %s, want %s", added[ndx], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	// Gates are checked too, these datasets have none
+	gateNames := GateNames
+	GateNames = nil
+	t.Cleanup(func() { GateNames = gateNames })
+	dataFile := func(planets string) string {
+		return writeTestFile(t, "atsdata.json", `{"ATS_Navcomp_DB": {"version": 1, "empires": [{"name": "Federation", "borders": [{"name": "Federation", "radius": 100}], "planets": [`+planets+`]}]}}`)
+	}
+	good := dataFile(`{"name": "Vulcan", "x": 1, "y": 2, "z": 3, "cochranes": 1500}`)
+	warning := dataFile(`{"name": "Vulcan", "x": 1, "y": 2, "z": 3}`)
+	duplicate := dataFile(`{"name": "Vulcan", "x": 1, "y": 2, "z": 3, "cochranes": 1500}, {"name": "vulcan", "x": 4, "y": 5, "z": 6, "cochranes": 1500}`)
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "clean", args: []string{good}, want: EXIT_OK},
+		{name: "clean and strict", args: []string{"-strict", good}, want: EXIT_OK},
+		{name: "warning", args: []string{warning}, want: EXIT_OK},
+		{name: "warning and strict", args: []string{"-strict", warning}, want: EXIT_ERROR},
+		{name: "error", args: []string{duplicate}, want: EXIT_ERROR},
+		{name: "two files", args: []string{good, warning}, want: EXIT_USAGE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runValidate(&Config{}, tt.args); exitCode(err) != tt.want {
+				t.Errorf("runValidate(%q) = %v, want exit %d", tt.args, err, tt.want)
+			}
+		})
+	}
+}